@@ -0,0 +1,41 @@
+// SPDX-License-Identifier: MIT-0
+
+package iputils
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+)
+
+// ipToInt returns the value of ip as an integer
+func ipToInt(ip net.IP) *big.Int {
+	return new(big.Int).SetBytes(ip)
+}
+
+// intToIP returns the address of the given size with the value of i.
+// The value must fit into the address.
+func intToIP(i *big.Int, size int) net.IP {
+	return i.FillBytes(make([]byte, size))
+}
+
+// networkFirstAndSize returns the first address of the network (in the size
+// of its mask) and the number of addresses in the network.
+func networkFirstAndSize(n *net.IPNet) (first net.IP, size *big.Int, err error) {
+	if n == nil {
+		return nil, nil, fmt.Errorf("%w: network is not specified", ErrInvalidNetwork)
+	}
+	ones, bits := n.Mask.Size()
+	if ones == 0 && bits == 0 {
+		return nil, nil, fmt.Errorf("%w: network %v has non-contiguous mask", ErrInvalidNetwork, n)
+	}
+	ip := n.IP.To16()
+	if bits == IPv4Size*8 {
+		ip = n.IP.To4()
+	}
+	if ip == nil {
+		return nil, nil, fmt.Errorf("%w %v", ErrInvalidNetwork, n)
+	}
+	size = new(big.Int).Lsh(big.NewInt(1), uint(bits-ones))
+	return ip.Mask(n.Mask), size, nil
+}
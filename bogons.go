@@ -0,0 +1,134 @@
+// SPDX-License-Identifier: MIT-0
+
+package iputils
+
+import (
+	"net"
+	"sync"
+)
+
+// BogonsVersion identifies the built-in list of bogon networks
+const BogonsVersion = "2026.10.1"
+
+// builtinBogons are networks which should never appear as source or
+// destination on the public internet: special-purpose blocks from the IANA
+// IPv4 and IPv6 registries, private and shared address space, documentation
+// and benchmarking blocks, multicast and reserved space.
+//
+// The IPv4-mapped block ::ffff:0:0/96 is not listed: an IPv4-mapped address
+// is checked as the IPv4 address it represents.
+var builtinBogons = []string{
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"127.0.0.0/8",
+	"169.254.0.0/16",
+	"172.16.0.0/12",
+	"192.0.0.0/24",
+	"192.0.2.0/24",
+	"192.168.0.0/16",
+	"198.18.0.0/15",
+	"198.51.100.0/24",
+	"203.0.113.0/24",
+	"224.0.0.0/4",
+	"240.0.0.0/4",
+
+	"::/96",
+	"100::/64",
+	"2001:2::/48",
+	"2001:10::/28",
+	"2001:db8::/32",
+	"3fff::/20",
+	"fc00::/7",
+	"fe80::/10",
+	"fec0::/10",
+	"ff00::/8",
+}
+
+// unassignedIPv6 are the blocks of the IANA IPv6 address space registry
+// which are not allocated for global unicast, ULA, link-local or multicast
+// use: everything outside of 2000::/3, fc00::/7, fe80::/10 and ff00::/8.
+var unassignedIPv6 = []string{
+	"::/8",
+	"100::/8",
+	"200::/7",
+	"400::/6",
+	"800::/5",
+	"1000::/4",
+	"4000::/2",
+	"8000::/2",
+	"c000::/3",
+	"e000::/4",
+	"f000::/5",
+	"f800::/6",
+	"fe00::/9",
+}
+
+// unassignedIPv6Holes are the globally reachable networks inside of
+// unassignedIPv6: the IPv4-mapped block (see builtinBogons) and the NAT64
+// well-known prefix.
+var unassignedIPv6Holes = []string{
+	"::ffff:0:0/96",
+	"64:ff9b::/96",
+}
+
+var (
+	bogonsLock    sync.RWMutex
+	bogonsCurrent = loadBuiltinBogons()
+)
+
+func loadBuiltinBogons() *IPSet {
+	var s IPSet
+	for _, n := range mustParseCIDRs(builtinBogons) {
+		s.AddNetwork(n)
+	}
+	holes := mustParseCIDRs(unassignedIPv6Holes)
+	for _, n := range mustParseCIDRs(unassignedIPv6) {
+		for _, r := range FindGaps(n, holes) {
+			s.AddRange(r)
+		}
+	}
+	return &s
+}
+
+// mustParseCIDRs parses the built-in networks and panics on failure
+func mustParseCIDRs(cidrs []string) []*net.IPNet {
+	result := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		result[i] = n
+	}
+	return result
+}
+
+// Bogons returns a copy of the set of bogon networks currently in use: the
+// built-in list (see BogonsVersion) or the one installed with SetBogons.
+func Bogons() *IPSet {
+	bogonsLock.RLock()
+	defer bogonsLock.RUnlock()
+	return bogonsCurrent.clone()
+}
+
+// SetBogons replaces the set of bogon networks used by Bogons and IsBogon,
+// e.g. with an up-to-date list downloaded at runtime.  Passing nil restores
+// the built-in list.
+func SetBogons(s *IPSet) {
+	if s == nil {
+		s = loadBuiltinBogons()
+	} else {
+		s = s.clone()
+	}
+	bogonsLock.Lock()
+	defer bogonsLock.Unlock()
+	bogonsCurrent = s
+}
+
+// IsBogon reports whether ip belongs to the set of bogon networks.
+func IsBogon(ip net.IP) bool {
+	bogonsLock.RLock()
+	defer bogonsLock.RUnlock()
+	return bogonsCurrent.Contains(ip)
+}
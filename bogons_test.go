@@ -0,0 +1,74 @@
+// SPDX-License-Identifier: MIT-0
+
+package iputils
+
+import (
+	"net"
+	"testing"
+)
+
+func TestIsBogon(t *testing.T) {
+	type testCase struct {
+		ip     net.IP
+		result bool
+	}
+	cases := []testCase{
+		testCase{net.ParseIP("10.1.2.3"), true},
+		testCase{net.IP{192, 168, 1, 1}, true},
+		testCase{net.ParseIP("127.0.0.1"), true},
+		testCase{net.ParseIP("100.64.0.1"), true},
+		testCase{net.ParseIP("255.255.255.255"), true},
+		testCase{net.ParseIP("::ffff:10.0.0.1"), true},
+		testCase{net.ParseIP("2001:db8::1"), true},
+		testCase{net.ParseIP("::1"), true},
+		testCase{net.ParseIP("::"), true},
+		testCase{net.ParseIP("fe80::1"), true},
+		testCase{net.ParseIP("ff02::1"), true},
+		testCase{net.ParseIP("8.8.8.8"), false},
+		testCase{net.ParseIP("::ffff:8.8.8.8"), false},
+		testCase{net.ParseIP("2001:4860:4860::8888"), false},
+		testCase{net.ParseIP("4000::1"), true},
+		testCase{net.ParseIP("1::1"), true},
+		testCase{net.ParseIP("8000::1"), true},
+		testCase{net.ParseIP("fe00::1"), true},
+		testCase{net.ParseIP("::1:0:0:0"), true},
+		testCase{net.ParseIP("::fffe:ffff:ffff"), true},
+		testCase{net.ParseIP("64:ff9b::8.8.8.8"), false},
+		testCase{net.ParseIP("64:ff9b:1::1"), true},
+		testCase{net.ParseIP("2600::1"), false},
+		testCase{net.ParseIP("fd00::1"), true},
+		testCase{nil, false},
+	}
+	for _, test := range cases {
+		if result := IsBogon(test.ip); test.result != result {
+			t.Errorf("expecting %v, got %v for %v", test.result, result, test.ip)
+		}
+	}
+}
+
+func TestSetBogons(t *testing.T) {
+	defer SetBogons(nil)
+
+	var custom IPSet
+	custom.AddNetwork(mustParseCIDR("198.51.100.0/24"))
+	SetBogons(&custom)
+	custom.AddNetwork(mustParseCIDR("8.8.8.0/24"))
+
+	if IsBogon(net.ParseIP("10.0.0.1")) || IsBogon(net.ParseIP("8.8.8.8")) {
+		t.Errorf("custom bogon list is not in use")
+	}
+	if !IsBogon(net.ParseIP("198.51.100.1")) {
+		t.Errorf("custom bogon list is not in use")
+	}
+
+	SetBogons(nil)
+	if !IsBogon(net.ParseIP("10.0.0.1")) {
+		t.Errorf("built-in bogon list is not restored")
+	}
+
+	b := Bogons()
+	b.AddIP(net.ParseIP("8.8.8.8"))
+	if IsBogon(net.ParseIP("8.8.8.8")) {
+		t.Errorf("modifying the result of Bogons changed the bogon list")
+	}
+}
@@ -0,0 +1,113 @@
+// SPDX-License-Identifier: MIT-0
+
+package iputils
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+)
+
+// IPv4Form selects the form of IPv4 addresses produced by Canonicalizer.
+type IPv4Form int
+
+const (
+	// IPv4Unchanged keeps IPv4 addresses in the form they are given.
+	IPv4Unchanged IPv4Form = iota
+
+	// IPv4As4 converts IPv4 addresses to the 4-byte form.  In text IPv4-mapped
+	// addresses become dotted decimal ones.
+	IPv4As4
+
+	// IPv4AsMapped converts IPv4 addresses to the IPv4-mapped 16-byte form.
+	// In text they are written as "::ffff:192.0.2.1".
+	IPv4AsMapped
+)
+
+// TextForm selects how Canonicalizer writes addresses.
+type TextForm int
+
+const (
+	// TextUnchanged keeps the address text as given, unless the IPv4 form
+	// changes the address.
+	TextUnchanged TextForm = iota
+
+	// TextRFC5952 writes IPv6 addresses in the recommended form of RFC 5952:
+	// lowercase, without leading zeros, with the longest run of zero groups
+	// compressed.
+	TextRFC5952
+
+	// TextExpanded writes IPv6 addresses with all eight groups of four
+	// digits, e.g. for systems comparing addresses as strings.
+	TextExpanded
+)
+
+// Canonicalizer brings addresses to the canonical form required by some
+// consumer.  Components sharing a Canonicalizer agree on the form of
+// addresses they exchange.
+//
+// The zero value changes nothing.
+type Canonicalizer struct {
+	IPv4 IPv4Form
+	Text TextForm
+
+	// StripZone removes the zone ("%eth0") of IPv6 addresses.
+	StripZone bool
+
+	// LowercaseZone converts the zone to lower case.
+	LowercaseZone bool
+}
+
+// Canonicalize returns a copy of ip in the configured IPv4 form.  If ip is
+// not valid, nil is returned.
+func (c *Canonicalizer) Canonicalize(ip net.IP) net.IP {
+	switch {
+	case len(ip) != IPv4Size && len(ip) != IPv6Size:
+		return nil
+	case c.IPv4 == IPv4As4 && ip.To4() != nil:
+		return CopyIP(ip.To4())
+	case c.IPv4 == IPv4AsMapped:
+		return CopyIP(ip.To16())
+	}
+	return CopyIP(ip)
+}
+
+// CanonicalizeString parses the address with an optional zone and writes it
+// in the configured form.
+func (c *Canonicalizer) CanonicalizeString(s string) (string, error) {
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return "", fmt.Errorf("%w %q", ErrInvalidIP, s)
+	}
+	text := s
+	if i := strings.IndexByte(text, '%'); i >= 0 {
+		text = text[:i]
+	}
+
+	zone := addr.Zone()
+	if c.StripZone {
+		zone = ""
+	} else if c.LowercaseZone {
+		zone = strings.ToLower(zone)
+	}
+	converted := addr
+	switch {
+	case c.IPv4 == IPv4As4 && addr.Is4In6():
+		converted = addr.Unmap()
+	case c.IPv4 == IPv4AsMapped && addr.Is4():
+		converted = netip.AddrFrom16(addr.As16())
+	}
+	converted = converted.WithZone("")
+
+	switch {
+	case c.Text == TextRFC5952 || c.Text == TextUnchanged && converted != addr.WithZone(""):
+		text = converted.String()
+	case c.Text == TextExpanded:
+		text = converted.StringExpanded()
+	}
+	if zone != "" {
+		text += "%" + zone
+	}
+	return text, nil
+}
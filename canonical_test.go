@@ -0,0 +1,89 @@
+// SPDX-License-Identifier: MIT-0
+
+package iputils
+
+import (
+	"fmt"
+	"net"
+	"testing"
+)
+
+func TestCanonicalize(t *testing.T) {
+	type testCase struct {
+		form   IPv4Form
+		ip     net.IP
+		result net.IP
+	}
+	mapped := net.ParseIP("192.0.2.1")
+	cases := []testCase{
+		testCase{IPv4Unchanged, mapped, mapped},
+		testCase{IPv4Unchanged, mapped.To4(), mapped.To4()},
+		testCase{IPv4As4, mapped, mapped.To4()},
+		testCase{IPv4As4, mapped.To4(), mapped.To4()},
+		testCase{IPv4AsMapped, mapped.To4(), mapped},
+		testCase{IPv4As4, net.ParseIP("2001:db8::1"), net.ParseIP("2001:db8::1")},
+		testCase{IPv4AsMapped, net.ParseIP("2001:db8::1"), net.ParseIP("2001:db8::1")},
+		testCase{IPv4As4, net.IP{1, 2, 3}, nil},
+	}
+	for _, test := range cases {
+		c := Canonicalizer{IPv4: test.form}
+		result := c.Canonicalize(test.ip)
+		if len(result) != len(test.result) || !result.Equal(test.result) {
+			t.Errorf("expecting %#v, got %#v for %#v with form %v", test.result, result, test.ip, test.form)
+		}
+	}
+}
+
+func TestCanonicalizeString(t *testing.T) {
+	type testCase struct {
+		c      Canonicalizer
+		input  string
+		result string
+	}
+	cases := []testCase{
+		testCase{Canonicalizer{}, "2001:DB8:0::1%ETH0", "2001:DB8:0::1%ETH0"},
+		testCase{Canonicalizer{Text: TextRFC5952}, "2001:DB8:0:0:0::1", "2001:db8::1"},
+		testCase{Canonicalizer{Text: TextRFC5952}, "2001:db8:0:0:1:0:0:1", "2001:db8::1:0:0:1"},
+		testCase{Canonicalizer{Text: TextExpanded}, "2001:db8::1", "2001:0db8:0000:0000:0000:0000:0000:0001"},
+		testCase{Canonicalizer{Text: TextRFC5952, LowercaseZone: true}, "FE80::1%ETH0", "fe80::1%eth0"},
+		testCase{Canonicalizer{StripZone: true, LowercaseZone: true}, "FE80::1%ETH0", "FE80::1"},
+		testCase{Canonicalizer{LowercaseZone: true}, "FE80::1%ETH0", "FE80::1%eth0"},
+		testCase{Canonicalizer{IPv4: IPv4As4}, "::FFFF:192.0.2.1", "192.0.2.1"},
+		testCase{Canonicalizer{IPv4: IPv4As4}, "2001:DB8::1", "2001:DB8::1"},
+		testCase{Canonicalizer{IPv4: IPv4AsMapped}, "192.0.2.1", "::ffff:192.0.2.1"},
+		testCase{Canonicalizer{IPv4: IPv4AsMapped, Text: TextExpanded}, "192.0.2.1", "0000:0000:0000:0000:0000:ffff:c000:0201"},
+		testCase{Canonicalizer{Text: TextRFC5952}, "::ffff:192.0.2.1", "::ffff:192.0.2.1"},
+		testCase{Canonicalizer{Text: TextExpanded}, "192.0.2.1", "192.0.2.1"},
+	}
+	for _, test := range cases {
+		result, err := test.c.CanonicalizeString(test.input)
+		if err != nil {
+			t.Errorf("unexpected error %v for %q with %+v", err, test.input, test.c)
+			continue
+		}
+		if result != test.result {
+			t.Errorf("expecting %q, got %q for %q with %+v", test.result, result, test.input, test.c)
+		}
+	}
+
+	faultCases := []string{"", "192.0.2", "2001:db8::1::1", "192.0.2.1/24", "host.example"}
+	for _, test := range faultCases {
+		c := Canonicalizer{Text: TextRFC5952}
+		if result, err := c.CanonicalizeString(test); err == nil {
+			t.Errorf("didn't get an error for %q, got %q", test, result)
+		}
+	}
+}
+
+func ExampleCanonicalizer() {
+	c := Canonicalizer{IPv4: IPv4As4, Text: TextRFC5952, StripZone: true}
+	for _, s := range []string{"::ffff:10.0.0.1", "FE80:0:0::0001%Eth0", "2001:db8:0:0:1::"} {
+		result, _ := c.CanonicalizeString(s)
+		fmt.Println(result)
+	}
+
+	// Output:
+	// 10.0.0.1
+	// fe80::1
+	// 2001:db8:0:0:1::
+}
@@ -0,0 +1,121 @@
+// SPDX-License-Identifier: MIT-0
+
+package iputils
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+)
+
+// IPSetWireVersion is the version of the wire format produced by EncodeIPSet
+const IPSetWireVersion = 1
+
+// ipSetWireMagic starts every encoded set
+const ipSetWireMagic = "IPS"
+
+// maxVarint128Len is the maximal length of a 128-bit value in varint encoding
+const maxVarint128Len = 19
+
+// EncodeIPSet encodes the set in a compact wire format suitable for sending
+// large sets between services.
+//
+// The format (version 1) is:
+//
+//	"IPS"              3 bytes, magic
+//	version            1 byte, IPSetWireVersion
+//	count              unsigned varint, the number of ranges
+//	count records of:
+//	  gap              unsigned varint, the distance from the end of the
+//	                   previous range (or from address 0 for the first one)
+//	  length           unsigned varint, the number of addresses minus one
+//
+// Addresses are numbered in the 128-bit IPv6 address space, IPv4 addresses
+// being IPv4-mapped IPv6 ones.  Varints are little-endian base-128 numbers
+// (the encoding of encoding/binary extended to 128 bits), so small gaps and
+// ranges take few bytes regardless of the address family.  Ranges are
+// written in ascending order and never overlap.
+func EncodeIPSet(s *IPSet) []byte {
+	data := append([]byte(ipSetWireMagic), IPSetWireVersion)
+	data = binary.AppendUvarint(data, uint64(len(s.ranges)))
+	next := new(big.Int)
+	for _, r := range s.ranges {
+		first, last := ipToInt(r.First.To16()), ipToInt(r.Last.To16())
+		data = appendVarint128(data, new(big.Int).Sub(first, next))
+		data = appendVarint128(data, new(big.Int).Sub(last, first))
+		next = last.Add(last, big.NewInt(1))
+	}
+	return data
+}
+
+// DecodeIPSet decodes a set produced by EncodeIPSet.
+func DecodeIPSet(data []byte) (*IPSet, error) {
+	if len(data) < len(ipSetWireMagic)+1 || string(data[:len(ipSetWireMagic)]) != ipSetWireMagic {
+		return nil, fmt.Errorf("%w: IP set has bad magic", ErrInvalidEncoding)
+	}
+	if version := data[len(ipSetWireMagic)]; version != IPSetWireVersion {
+		return nil, fmt.Errorf("%w: unsupported IP set version %v", ErrInvalidEncoding, version)
+	}
+	data = data[len(ipSetWireMagic)+1:]
+	count, n := binary.Uvarint(data)
+	if n <= 0 {
+		return nil, fmt.Errorf("%w: IP set has bad range count", ErrInvalidEncoding)
+	}
+	data = data[n:]
+
+	var s IPSet
+	next := new(big.Int)
+	for i := uint64(0); i < count; i++ {
+		gap, n := readVarint128(data)
+		if n <= 0 {
+			return nil, fmt.Errorf("%w: IP set has bad gap of range #%v", ErrInvalidEncoding, i)
+		}
+		data = data[n:]
+		length, n := readVarint128(data)
+		if n <= 0 {
+			return nil, fmt.Errorf("%w: IP set has bad length of range #%v", ErrInvalidEncoding, i)
+		}
+		data = data[n:]
+
+		first := gap.Add(gap, next)
+		last := length.Add(length, first)
+		if last.BitLen() > IPv6Size*8 {
+			return nil, fmt.Errorf("%w: IP set range #%v is out of the address space", ErrInvalidEncoding, i)
+		}
+		s.AddRange(IPRange{intToIP(first, IPv6Size), intToIP(last, IPv6Size)})
+		next = new(big.Int).Add(last, big.NewInt(1))
+	}
+	if len(data) != 0 {
+		return nil, fmt.Errorf("%w: IP set has %v unexpected trailing bytes", ErrInvalidEncoding, len(data))
+	}
+	return &s, nil
+}
+
+// appendVarint128 appends the non-negative value of at most 128 bits in the
+// unsigned varint encoding.
+func appendVarint128(data []byte, v *big.Int) []byte {
+	v = new(big.Int).Set(v)
+	low := new(big.Int)
+	mask := big.NewInt(0x7f)
+	for v.BitLen() > 7 {
+		data = append(data, byte(low.And(v, mask).Uint64())|0x80)
+		v.Rsh(v, 7)
+	}
+	return append(data, byte(v.Uint64()))
+}
+
+// readVarint128 reads a value written by appendVarint128 and returns it with
+// the number of bytes read.  If the data is not valid, n is 0.
+func readVarint128(data []byte) (v *big.Int, n int) {
+	v = new(big.Int)
+	for i := 0; i < len(data) && i < maxVarint128Len; i++ {
+		v.Or(v, new(big.Int).Lsh(big.NewInt(int64(data[i]&0x7f)), uint(7*i)))
+		if data[i] < 0x80 {
+			if v.BitLen() > IPv6Size*8 {
+				return nil, 0
+			}
+			return v, i + 1
+		}
+	}
+	return nil, 0
+}
@@ -0,0 +1,70 @@
+// SPDX-License-Identifier: MIT-0
+
+package iputils
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEncodeIPSet(t *testing.T) {
+	type testCase struct {
+		specs []string
+		size  int
+	}
+	cases := []testCase{
+		testCase{nil, 5},
+		testCase{[]string{"10.0.0.1"}, 5 + 7 + 1},
+		testCase{[]string{"10.0.0.0/24", "10.0.2.0/24"}, 5 + 7 + 2 + 2 + 2},
+		testCase{[]string{"10.0.0.0/8", "192.168.0.0/16", "2001:db8::/32", "ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff"}, 0},
+		testCase{[]string{"::/0"}, 0},
+	}
+	for _, test := range cases {
+		s := mustParseSet(test.specs...)
+		data := EncodeIPSet(s)
+		if test.size != 0 && test.size != len(data) {
+			t.Errorf("expecting %v bytes, got %v for %v", test.size, len(data), s)
+		}
+		decoded, err := DecodeIPSet(data)
+		if err != nil {
+			t.Errorf("unexpected error %v when decoding %v", err, s)
+			continue
+		}
+		if s.String() != decoded.String() {
+			t.Errorf("expecting %v, got %v after decoding", s, decoded)
+		}
+		if !bytes.Equal(data, EncodeIPSet(decoded)) {
+			t.Errorf("encoding of %v is not stable", s)
+		}
+	}
+}
+
+func TestEncodeIPSetFormat(t *testing.T) {
+	data := EncodeIPSet(mustParseSet("::1-::2", "::81"))
+	expected := []byte{'I', 'P', 'S', 1, 2, 1, 1, 0x7e, 0}
+	if !bytes.Equal(expected, data) {
+		t.Errorf("expecting %v, got %v", expected, data)
+	}
+}
+
+func TestDecodeIPSetFaults(t *testing.T) {
+	valid := EncodeIPSet(mustParseSet("10.0.0.0/24", "2001:db8::/32"))
+	faultCases := [][]byte{
+		nil,
+		[]byte("IPX\x01\x00"),
+		[]byte("IPS\x02\x00"),
+		[]byte("IPS\x01"),
+		[]byte("IPS\x01\x01\x00"),
+		[]byte("IPS\x01\x01\x80"),
+		append([]byte("IPS\x01\x01"), bytes.Repeat([]byte{0xff}, 19)...),
+		append(append([]byte("IPS\x01\x01\x00"), bytes.Repeat([]byte{0xff}, 18)...), 0x7f),
+		append(append([]byte("IPS\x01\x02\x00"), bytes.Repeat([]byte{0xff}, 18)...), 0x03, 0x01, 0x00),
+		append(valid, 0),
+		valid[:len(valid)-1],
+	}
+	for _, test := range faultCases {
+		if s, err := DecodeIPSet(test); err == nil {
+			t.Errorf("didn't get an error when decoding %v, got %v", test, s)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+// SPDX-License-Identifier: MIT-0
+
+package iputils
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+)
+
+// DelegationStrategy defines the order in which delegations are taken out
+// of a parent network
+type DelegationStrategy int
+
+const (
+	// DelegateRightmost assigns delegations sequentially: the rightmost
+	// bits of the delegation number change first, so delegations are
+	// packed densely at the start of the parent network.
+	DelegateRightmost DelegationStrategy = iota
+
+	// DelegateLeftmost assigns delegations with the leftmost bits of the
+	// delegation number changing first (a binary chop): the first
+	// delegation is at the start of the parent, the second one in its
+	// middle, the next two in the middle of the quarters and so on.
+	// Delegations stay as far from each other as possible, so every one
+	// can later grow into the free space next to it without renumbering.
+	DelegateLeftmost
+)
+
+func (s DelegationStrategy) String() string {
+	switch s {
+	case DelegateRightmost:
+		return "rightmost"
+	case DelegateLeftmost:
+		return "leftmost"
+	}
+	return fmt.Sprintf("DelegationStrategy(%d)", int(s))
+}
+
+// DelegationAt returns the delegation with the given index when the parent
+// network is split into networks with the prefix length delegationLen
+// assigned in the order of strategy.
+func DelegationAt(parent *net.IPNet, delegationLen int, index uint64, strategy DelegationStrategy) (*net.IPNet, error) {
+	first, width, err := delegationWidth(parent, delegationLen)
+	if err != nil {
+		return nil, err
+	}
+	if width < 64 && index >= uint64(1)<<uint(width) {
+		return nil, fmt.Errorf("%w: network %v has only %v delegations of /%v, index %v is too big", ErrOutOfRange,
+			parent, uint64(1)<<uint(width), delegationLen, index)
+	}
+
+	number := new(big.Int)
+	switch strategy {
+	case DelegateRightmost:
+		number.SetUint64(index)
+	case DelegateLeftmost:
+		for i := 0; i < 64 && i < width; i++ {
+			if index&(1<<uint(i)) != 0 {
+				number.SetBit(number, width-1-i, 1)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("%w: unknown delegation strategy %v", ErrOutOfRange, strategy)
+	}
+
+	bits := len(first) * 8
+	value := number.Lsh(number, uint(bits-delegationLen))
+	value.Add(value, ipToInt(first))
+	return &net.IPNet{IP: intToIP(value, len(first)), Mask: net.CIDRMask(delegationLen, bits)}, nil
+}
+
+// MaxPlannedDelegations is the biggest number of delegations PlanDelegations
+// returns at once.  Use DelegationAt to walk through more of them.
+const MaxPlannedDelegations = 1 << 20
+
+// PlanDelegations returns the first count delegations with the prefix length
+// delegationLen taken out of the parent network in the order of strategy.
+// The count is limited by MaxPlannedDelegations.
+func PlanDelegations(parent *net.IPNet, delegationLen int, count int, strategy DelegationStrategy) ([]*net.IPNet, error) {
+	_, width, err := delegationWidth(parent, delegationLen)
+	if err != nil {
+		return nil, err
+	}
+	if count < 0 || (width < 63 && uint64(count) > uint64(1)<<uint(width)) {
+		return nil, fmt.Errorf("%w: network %v can't be split into %v delegations of /%v", ErrOutOfRange, parent, count, delegationLen)
+	}
+	if count > MaxPlannedDelegations {
+		return nil, fmt.Errorf("%w: %v delegations requested, at most %v can be planned at once", ErrOutOfRange, count, MaxPlannedDelegations)
+	}
+	result := make([]*net.IPNet, count)
+	for i := range result {
+		if result[i], err = DelegationAt(parent, delegationLen, uint64(i), strategy); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// delegationWidth validates the parent network and the delegation length and
+// returns the first address of the parent and the number of bits which
+// number the delegations.
+func delegationWidth(parent *net.IPNet, delegationLen int) (net.IP, int, error) {
+	first, _, err := networkFirstAndSize(parent)
+	if err != nil {
+		return nil, 0, err
+	}
+	ones, bits := parent.Mask.Size()
+	if delegationLen < ones || delegationLen > bits {
+		return nil, 0, fmt.Errorf("%w: delegation length /%v doesn't fit into network %v", ErrOutOfRange, delegationLen, parent)
+	}
+	return first, delegationLen - ones, nil
+}
@@ -0,0 +1,106 @@
+// SPDX-License-Identifier: MIT-0
+
+package iputils
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"testing"
+)
+
+func TestPlanDelegations(t *testing.T) {
+	type testCase struct {
+		parent        string
+		delegationLen int
+		count         int
+		strategy      DelegationStrategy
+		result        string
+	}
+	cases := []testCase{
+		testCase{"2001:db8::/40", 56, 3, DelegateRightmost, "[2001:db8::/56 2001:db8:0:100::/56 2001:db8:0:200::/56]"},
+		testCase{"2001:db8::/40", 56, 5, DelegateLeftmost,
+			"[2001:db8::/56 2001:db8:80::/56 2001:db8:40::/56 2001:db8:c0::/56 2001:db8:20::/56]"},
+		testCase{"10.0.0.0/22", 24, 4, DelegateLeftmost, "[10.0.0.0/24 10.0.2.0/24 10.0.1.0/24 10.0.3.0/24]"},
+		testCase{"10.0.0.0/22", 24, 4, DelegateRightmost, "[10.0.0.0/24 10.0.1.0/24 10.0.2.0/24 10.0.3.0/24]"},
+		testCase{"10.0.0.0/24", 24, 1, DelegateLeftmost, "[10.0.0.0/24]"},
+		testCase{"10.0.0.0/24", 26, 0, DelegateLeftmost, "[]"},
+		testCase{"2001:db8::/32", 128, 2, DelegateLeftmost, "[2001:db8::/128 2001:db8:8000::/128]"},
+	}
+	for _, test := range cases {
+		result, err := PlanDelegations(mustParseCIDR(test.parent), test.delegationLen, test.count, test.strategy)
+		if err != nil {
+			t.Errorf("unexpected error %v when planning %v /%v delegations of %v", err, test.count, test.delegationLen, test.parent)
+			continue
+		}
+		if test.result != fmt.Sprint(result) {
+			t.Errorf("expecting %v, got %v when planning %v %v /%v delegations of %v",
+				test.result, result, test.count, test.strategy, test.delegationLen, test.parent)
+		}
+	}
+}
+
+func TestPlanDelegationsFaults(t *testing.T) {
+	type faultCase struct {
+		parent        *net.IPNet
+		delegationLen int
+		count         int
+		strategy      DelegationStrategy
+	}
+	faultCases := []faultCase{
+		faultCase{mustParseCIDR("10.0.0.0/22"), 24, 5, DelegateLeftmost},
+		faultCase{mustParseCIDR("10.0.0.0/22"), 21, 1, DelegateLeftmost},
+		faultCase{mustParseCIDR("10.0.0.0/22"), 33, 1, DelegateLeftmost},
+		faultCase{mustParseCIDR("10.0.0.0/22"), 24, -1, DelegateLeftmost},
+		faultCase{mustParseCIDR("10.0.0.0/22"), 24, 1, DelegationStrategy(7)},
+		faultCase{nil, 24, 1, DelegateLeftmost},
+		faultCase{mustParseCIDR("2001:db8::/32"), 64, MaxPlannedDelegations + 1, DelegateRightmost},
+	}
+	for _, test := range faultCases {
+		if result, err := PlanDelegations(test.parent, test.delegationLen, test.count, test.strategy); err == nil {
+			t.Errorf("didn't get an error when planning %v /%v delegations of %v, got %v",
+				test.count, test.delegationLen, test.parent, result)
+		}
+	}
+}
+
+func TestDelegationAt(t *testing.T) {
+	type testCase struct {
+		parent        string
+		delegationLen int
+		index         uint64
+		strategy      DelegationStrategy
+		result        string
+	}
+	cases := []testCase{
+		testCase{"2001:db8::/40", 56, 0xffff, DelegateRightmost, "2001:db8:ff:ff00::/56"},
+		testCase{"2001:db8::/40", 56, 1, DelegateLeftmost, "2001:db8:80::/56"},
+		testCase{"::/0", 128, math.MaxUint64, DelegateRightmost, "::ffff:ffff:ffff:ffff/128"},
+		testCase{"::/0", 128, 1, DelegateLeftmost, "8000::/128"},
+		testCase{"::/0", 128, 1 << 63, DelegateLeftmost, "0:0:0:1::/128"},
+	}
+	for _, test := range cases {
+		result, err := DelegationAt(mustParseCIDR(test.parent), test.delegationLen, test.index, test.strategy)
+		if err != nil || test.result != result.String() {
+			t.Errorf("expecting %v, got (%v, %v) for %v delegation #%v of /%v in %v",
+				test.result, result, err, test.strategy, test.index, test.delegationLen, test.parent)
+		}
+	}
+	if result, err := DelegationAt(mustParseCIDR("2001:db8::/40"), 56, 1<<16, DelegateRightmost); err == nil {
+		t.Errorf("didn't get an error for index out of range, got %v", result)
+	}
+}
+
+func ExamplePlanDelegations() {
+	parent := mustParseCIDR("2001:db8::/40")
+	delegations, _ := PlanDelegations(parent, 56, 4, DelegateLeftmost)
+	for _, d := range delegations {
+		fmt.Println(d)
+	}
+
+	// Output:
+	// 2001:db8::/56
+	// 2001:db8:80::/56
+	// 2001:db8:40::/56
+	// 2001:db8:c0::/56
+}
@@ -0,0 +1,80 @@
+// SPDX-License-Identifier: MIT-0
+
+package iputils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+	"net"
+)
+
+// DeriveIP returns an address inside the network chosen by hashing the key.
+// The same key always produces the same address in the same network.
+// If the network is not valid, nil is returned.
+func DeriveIP(n *net.IPNet, key []byte) net.IP {
+	first, size, err := networkFirstAndSize(n)
+	if err != nil {
+		return nil
+	}
+	return intToIP(deriveOffset(first, size, key), len(first))
+}
+
+// DeriveFree works like DeriveIP, but skips addresses for which used returns
+// true.  Starting from the derived address, the following addresses of the
+// network (wrapping around at its end) are probed until a free one is
+// found.  An error is returned if every address of the network is used.
+func DeriveFree(n *net.IPNet, key []byte, used func(ip net.IP) bool) (net.IP, error) {
+	first, size, err := networkFirstAndSize(n)
+	if err != nil {
+		return nil, err
+	}
+	start := ipToInt(first)
+	end := new(big.Int).Add(start, size)
+	value := deriveOffset(first, size, key)
+	one := big.NewInt(1)
+	for i := new(big.Int); i.Cmp(size) < 0; i.Add(i, one) {
+		ip := intToIP(value, len(first))
+		if !used(ip) {
+			return ip, nil
+		}
+		if value.Add(value, one).Cmp(end) == 0 {
+			value.Set(start)
+		}
+	}
+	return nil, fmt.Errorf("%w in network %v", ErrPoolExhausted, n)
+}
+
+// MapIntoNetwork deterministically maps ip to an address inside the target
+// network.  The mapping is keyed: the same ip, target and key always give
+// the same address, while different keys give unrelated mappings.  The
+// source address is taken in the 16-byte form, so an IPv4 address maps the
+// same way as its IPv4-mapped IPv6 equivalent.  The result has the size of
+// the target network address.  If ip or the network are not valid, nil is
+// returned.
+func MapIntoNetwork(ip net.IP, target *net.IPNet, key []byte) net.IP {
+	first, size, err := networkFirstAndSize(target)
+	ip16 := ip.To16()
+	if err != nil || ip16 == nil {
+		return nil
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(ip16)
+	return intToIP(reduceToNetwork(first, size, mac.Sum(nil)), len(first))
+}
+
+// deriveOffset returns the value of the address inside the network selected
+// by the key.
+func deriveOffset(first net.IP, size *big.Int, key []byte) *big.Int {
+	sum := sha256.Sum256(key)
+	return reduceToNetwork(first, size, sum[:])
+}
+
+// reduceToNetwork returns the value of the address inside the network
+// selected by the hash.
+func reduceToNetwork(first net.IP, size *big.Int, hash []byte) *big.Int {
+	offset := new(big.Int).SetBytes(hash)
+	offset.Mod(offset, size)
+	return offset.Add(offset, ipToInt(first))
+}
@@ -0,0 +1,106 @@
+// SPDX-License-Identifier: MIT-0
+
+package iputils
+
+import (
+	"net"
+	"testing"
+)
+
+func TestDeriveIP(t *testing.T) {
+	networks := []*net.IPNet{
+		mustParseCIDR("10.20.0.0/16"),
+		mustParseCIDR("192.168.1.7/30"),
+		mustParseCIDR("192.168.1.1/32"),
+		mustParseCIDR("2001:db8::/64"),
+		&net.IPNet{IP: net.ParseIP("172.16.0.0"), Mask: net.CIDRMask(12, 32)},
+	}
+	keys := []string{"", "node-1", "node-2", "some.service.example"}
+	for _, n := range networks {
+		for _, key := range keys {
+			ip := DeriveIP(n, []byte(key))
+			if ip == nil || !n.Contains(ip) {
+				t.Errorf("derived address %v for key %q is not in %v", ip, key, n)
+				continue
+			}
+			if n.IP.To4() != nil && len(ip) != IPv4Size {
+				t.Errorf("expecting 4-byte address for %v, got %v", n, []byte(ip))
+			}
+			if again := DeriveIP(n, []byte(key)); !ip.Equal(again) {
+				t.Errorf("expecting stable address %v for key %q in %v, got %v", ip, key, n, again)
+			}
+		}
+	}
+	if ip := DeriveIP(&net.IPNet{IP: net.ParseIP("10.0.0.0"), Mask: net.IPMask{255, 0, 255, 0}}, nil); ip != nil {
+		t.Errorf("expecting nil for invalid network, got %v", ip)
+	}
+	if ip := DeriveIP(nil, nil); ip != nil {
+		t.Errorf("expecting nil for nil network, got %v", ip)
+	}
+}
+
+func TestDeriveFree(t *testing.T) {
+	n := mustParseCIDR("192.168.0.0/29")
+	used := map[string]bool{}
+	for i := 0; i < 8; i++ {
+		ip, err := DeriveFree(n, []byte("key"), func(ip net.IP) bool { return used[ip.String()] })
+		if err != nil {
+			t.Fatalf("unexpected error %v after %v allocations", err, i)
+		}
+		if !n.Contains(ip) || used[ip.String()] {
+			t.Fatalf("derived address %v is not free in %v", ip, n)
+		}
+		if i == 0 && !ip.Equal(DeriveIP(n, []byte("key"))) {
+			t.Errorf("expecting %v as the first free address, got %v", DeriveIP(n, []byte("key")), ip)
+		}
+		used[ip.String()] = true
+	}
+	if ip, err := DeriveFree(n, []byte("key"), func(ip net.IP) bool { return used[ip.String()] }); err == nil {
+		t.Errorf("didn't get an error for exhausted network, got %v", ip)
+	}
+}
+
+func TestMapIntoNetwork(t *testing.T) {
+	targets := []*net.IPNet{
+		mustParseCIDR("100.64.0.0/10"),
+		mustParseCIDR("192.168.7.0/28"),
+		mustParseCIDR("2001:db8:1::/48"),
+	}
+	sources := []net.IP{
+		net.ParseIP("10.0.0.1"),
+		net.ParseIP("10.0.0.2"),
+		net.ParseIP("2001:db8::1"),
+	}
+	for _, target := range targets {
+		for _, ip := range sources {
+			mapped := MapIntoNetwork(ip, target, []byte("tenant-a"))
+			if mapped == nil || !target.Contains(mapped) || len(mapped) != len(target.IP) {
+				t.Errorf("address %v mapped to %v outside of %v", ip, mapped, target)
+				continue
+			}
+			if again := MapIntoNetwork(CopyIP(ip), target, []byte("tenant-a")); !mapped.Equal(again) {
+				t.Errorf("expecting stable mapping of %v to %v in %v, got %v", ip, mapped, target, again)
+			}
+			if ip4 := ip.To4(); ip4 != nil {
+				if again := MapIntoNetwork(ip4, target, []byte("tenant-a")); !mapped.Equal(again) {
+					t.Errorf("expecting 4-byte and 16-byte forms of %v to map to %v, got %v", ip, mapped, again)
+				}
+			}
+		}
+	}
+
+	target := mustParseCIDR("2001:db8:1::/48")
+	a := MapIntoNetwork(net.ParseIP("10.0.0.1"), target, []byte("tenant-a"))
+	b := MapIntoNetwork(net.ParseIP("10.0.0.1"), target, []byte("tenant-b"))
+	c := MapIntoNetwork(net.ParseIP("10.0.0.2"), target, []byte("tenant-a"))
+	if a.Equal(b) || a.Equal(c) {
+		t.Errorf("expecting different addresses for different keys and sources, got %v, %v, %v", a, b, c)
+	}
+
+	if ip := MapIntoNetwork(nil, target, nil); ip != nil {
+		t.Errorf("expecting nil for invalid source, got %v", ip)
+	}
+	if ip := MapIntoNetwork(net.ParseIP("10.0.0.1"), nil, nil); ip != nil {
+		t.Errorf("expecting nil for invalid target, got %v", ip)
+	}
+}
@@ -0,0 +1,180 @@
+// SPDX-License-Identifier: MIT-0
+
+package iputils
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// PortRange is a range of ports from First to Last.  The last port is
+// included into the range.
+type PortRange struct {
+	First uint16
+	Last  uint16
+}
+
+// AllPorts is the range of all ports
+var AllPorts = PortRange{0, 65535}
+
+func (r PortRange) String() string {
+	if r.First == r.Last {
+		return strconv.Itoa(int(r.First))
+	}
+	return fmt.Sprintf("%d-%d", r.First, r.Last)
+}
+
+// Contains reports whether port belongs to the range.
+func (r PortRange) Contains(port uint16) bool {
+	return r.First <= port && port <= r.Last
+}
+
+// Endpoint pairs a range of ip addresses with a range of ports, e.g. a
+// network with a port range as used in ACLs.
+type Endpoint struct {
+	IPs   IPRange
+	Ports PortRange
+}
+
+// ParseEndpoint parses an address part accepted by ParseAny optionally
+// followed by a colon and a port or a port range, e.g. "10.0.0.0/24:8080-8090",
+// "192.168.0.1:53" or "[2001:db8::/32]:443".  IPv6 addresses followed by
+// ports must be enclosed in square brackets.  If the port part is omitted or
+// is "*", the endpoint covers all ports.
+func ParseEndpoint(s string) (Endpoint, error) {
+	s = strings.TrimSpace(s)
+	addr, ports := s, "*"
+	if strings.HasPrefix(s, "[") {
+		end := strings.Index(s, "]")
+		if end < 0 {
+			return Endpoint{}, fmt.Errorf("%w %q: missing ']'", ErrInvalidEndpoint, s)
+		}
+		addr = s[1:end]
+		if rest := s[end+1:]; rest != "" {
+			if !strings.HasPrefix(rest, ":") {
+				return Endpoint{}, fmt.Errorf("%w %q", ErrInvalidEndpoint, s)
+			}
+			ports = rest[1:]
+		}
+	} else if strings.Count(s, ":") == 1 {
+		i := strings.Index(s, ":")
+		addr, ports = s[:i], s[i+1:]
+	}
+
+	e, err := ParseAny(addr)
+	if err != nil {
+		return Endpoint{}, fmt.Errorf("%w in %q", err, s)
+	}
+	portRange, err := parsePortRange(ports)
+	if err != nil {
+		return Endpoint{}, fmt.Errorf("%w in %q", err, s)
+	}
+	return Endpoint{e.Range, portRange}, nil
+}
+
+func parsePortRange(s string) (PortRange, error) {
+	if s == "*" {
+		return AllPorts, nil
+	}
+	parts := strings.Split(s, "-")
+	if len(parts) > 2 {
+		return PortRange{}, fmt.Errorf("%w: port range %q", ErrInvalidEndpoint, s)
+	}
+	var values [2]uint16
+	for i, part := range parts {
+		value, err := strconv.ParseUint(part, 10, 16)
+		if err != nil {
+			return PortRange{}, fmt.Errorf("%w: port range %q", ErrInvalidEndpoint, s)
+		}
+		values[i] = uint16(value)
+	}
+	if len(parts) == 1 {
+		values[1] = values[0]
+	}
+	if values[0] > values[1] {
+		return PortRange{}, fmt.Errorf("%w: port range %q starts after its end", ErrInvalidEndpoint, s)
+	}
+	return PortRange{values[0], values[1]}, nil
+}
+
+// String formats the endpoint the way ParseEndpoint accepts it.  The zero
+// value is formatted as an empty string.
+func (e Endpoint) String() string {
+	if e.IPs.First == nil || e.IPs.Last == nil {
+		return ""
+	}
+	addr := e.IPs.String()
+	if n, ok := RangeIsCIDR(e.IPs.First, e.IPs.Last); ok {
+		addr = n.String()
+		if ones, bits := n.Mask.Size(); ones == bits {
+			addr = n.IP.String()
+		}
+	}
+	if e.IPs.First.To4() == nil {
+		addr = "[" + addr + "]"
+	}
+	if e.Ports == AllPorts {
+		return addr
+	}
+	return addr + ":" + e.Ports.String()
+}
+
+// Contains reports whether the address and the port belong to the endpoint.
+func (e Endpoint) Contains(ip net.IP, port uint16) bool {
+	return e.Ports.Contains(port) && e.IPs.Contains(ip)
+}
+
+// Overlaps reports whether some address and port belong to both endpoints.
+func (e Endpoint) Overlaps(other Endpoint) bool {
+	_, ok := e.Intersect(other)
+	return ok
+}
+
+// Intersect returns the addresses and ports belonging to both endpoints and
+// true.  If the endpoints don't overlap, false is returned.
+func (e Endpoint) Intersect(other Endpoint) (Endpoint, bool) {
+	ports, ok := intersectPorts(e.Ports, other.Ports)
+	if !ok {
+		return Endpoint{}, false
+	}
+	ips, ok := Intersect(e.IPs, other.IPs)
+	if !ok {
+		return Endpoint{}, false
+	}
+	return Endpoint{ips, ports}, true
+}
+
+// Subtract returns the addresses and ports of the endpoint which don't
+// belong to other, as a list of non-overlapping endpoints.
+func (e Endpoint) Subtract(other Endpoint) []Endpoint {
+	overlap, ok := e.Intersect(other)
+	if !ok {
+		return []Endpoint{e}
+	}
+	var result []Endpoint
+	for _, part := range splitRangeByFamily(normalizeRange(e.IPs)) {
+		for _, ips := range subtractSorted(part, []IPRange{overlap.IPs}) {
+			result = append(result, Endpoint{ips, e.Ports})
+		}
+	}
+	if e.Ports.First < overlap.Ports.First {
+		result = append(result, Endpoint{overlap.IPs, PortRange{e.Ports.First, overlap.Ports.First - 1}})
+	}
+	if overlap.Ports.Last < e.Ports.Last {
+		result = append(result, Endpoint{overlap.IPs, PortRange{overlap.Ports.Last + 1, e.Ports.Last}})
+	}
+	return result
+}
+
+func intersectPorts(a, b PortRange) (PortRange, bool) {
+	r := a
+	if b.First > r.First {
+		r.First = b.First
+	}
+	if b.Last < r.Last {
+		r.Last = b.Last
+	}
+	return r, r.First <= r.Last
+}
@@ -0,0 +1,135 @@
+// SPDX-License-Identifier: MIT-0
+
+package iputils
+
+import (
+	"fmt"
+	"net"
+	"testing"
+)
+
+func mustParseEndpoint(s string) Endpoint {
+	e, err := ParseEndpoint(s)
+	if err != nil {
+		panic(err)
+	}
+	return e
+}
+
+func TestParseEndpoint(t *testing.T) {
+	type testCase struct {
+		input  string
+		ips    string
+		ports  PortRange
+		result string
+	}
+	cases := []testCase{
+		testCase{"10.0.0.0/24:8080-8090", "10.0.0.0-10.0.0.255", PortRange{8080, 8090}, "10.0.0.0/24:8080-8090"},
+		testCase{"192.168.0.1:53", "192.168.0.1-192.168.0.1", PortRange{53, 53}, "192.168.0.1:53"},
+		testCase{"192.168.0.1", "192.168.0.1-192.168.0.1", AllPorts, "192.168.0.1"},
+		testCase{"192.168.0.1:*", "192.168.0.1-192.168.0.1", AllPorts, "192.168.0.1"},
+		testCase{"10.0.0.1-10.0.0.5:0-1023", "10.0.0.1-10.0.0.5", PortRange{0, 1023}, "10.0.0.1-10.0.0.5:0-1023"},
+		testCase{"[2001:db8::/32]:443", "2001:db8::-2001:db8:ffff:ffff:ffff:ffff:ffff:ffff", PortRange{443, 443},
+			"[2001:db8::/32]:443"},
+		testCase{"[2001:db8::1]", "2001:db8::1-2001:db8::1", AllPorts, "[2001:db8::1]"},
+		testCase{"2001:db8::1", "2001:db8::1-2001:db8::1", AllPorts, "[2001:db8::1]"},
+	}
+	for _, test := range cases {
+		e, err := ParseEndpoint(test.input)
+		if err != nil {
+			t.Errorf("unexpected error %v when parsing %q", err, test.input)
+			continue
+		}
+		if test.ips != e.IPs.String() || test.ports != e.Ports || test.result != e.String() {
+			t.Errorf("expecting (%v, %v, %v), got (%v, %v, %v) when parsing %q",
+				test.ips, test.ports, test.result, e.IPs, e.Ports, e, test.input)
+		}
+	}
+	if s := (Endpoint{}).String(); s != "" {
+		t.Errorf("expecting an empty string for the zero value, got %q", s)
+	}
+}
+
+func TestParseEndpointFaults(t *testing.T) {
+	faultCases := []string{
+		"",
+		"10.0.0.0/24:",
+		"10.0.0.0/24:65536",
+		"10.0.0.0/24:90-80",
+		"10.0.0.0/24:1-2-3",
+		"10.0.0.0/24:http",
+		"[2001:db8::1",
+		"[2001:db8::1]80",
+		"[2001:db8::1]:",
+		"foo:80",
+	}
+	for _, test := range faultCases {
+		if e, err := ParseEndpoint(test); err == nil {
+			t.Errorf("didn't get an error when parsing %q, got %v", test, e)
+		}
+	}
+}
+
+func TestEndpointContains(t *testing.T) {
+	e := mustParseEndpoint("10.0.0.0/24:8080-8090")
+	type testCase struct {
+		ip     net.IP
+		port   uint16
+		result bool
+	}
+	cases := []testCase{
+		testCase{net.ParseIP("10.0.0.1"), 8080, true},
+		testCase{net.IP{10, 0, 0, 255}, 8090, true},
+		testCase{net.ParseIP("10.0.0.1"), 8091, false},
+		testCase{net.ParseIP("10.0.1.1"), 8080, false},
+	}
+	for _, test := range cases {
+		if result := e.Contains(test.ip, test.port); test.result != result {
+			t.Errorf("expecting %v, got %v for %v:%v in %v", test.result, result, test.ip, test.port, e)
+		}
+	}
+}
+
+func TestEndpointIntersect(t *testing.T) {
+	type testCase struct {
+		a      string
+		b      string
+		result string
+	}
+	cases := []testCase{
+		testCase{"10.0.0.0/24:8080-8090", "10.0.0.128/25:8085-9000", "10.0.0.128/25:8085-8090"},
+		testCase{"10.0.0.0/24:8080-8090", "10.0.0.0/8", "10.0.0.0/24:8080-8090"},
+		testCase{"10.0.0.0/24:8080-8090", "10.0.0.0/24:80", ""},
+		testCase{"10.0.0.0/24:8080-8090", "10.0.1.0/24:8080", ""},
+		testCase{"[2001:db8::/32]:443", "10.0.0.0/8:443", ""},
+	}
+	for _, test := range cases {
+		a, b := mustParseEndpoint(test.a), mustParseEndpoint(test.b)
+		result, ok := a.Intersect(b)
+		if ok != (test.result != "") || (ok && test.result != result.String()) || ok != a.Overlaps(b) {
+			t.Errorf("expecting %q, got (%v, %v) when intersecting %v and %v", test.result, result, ok, a, b)
+		}
+	}
+}
+
+func TestEndpointSubtract(t *testing.T) {
+	type testCase struct {
+		a      string
+		b      string
+		result string
+	}
+	cases := []testCase{
+		testCase{"10.0.0.0/24:8080-8090", "10.0.1.0/24", "[10.0.0.0/24:8080-8090]"},
+		testCase{"10.0.0.0/24:8080-8090", "10.0.0.0/8", "[]"},
+		testCase{"10.0.0.0/24:8080-8090", "10.0.0.0/25:8085",
+			"[10.0.0.128/25:8080-8090 10.0.0.0/25:8080-8084 10.0.0.0/25:8086-8090]"},
+		testCase{"10.0.0.0/24", "10.0.0.10:0-1023",
+			"[10.0.0.0-10.0.0.9 10.0.0.11-10.0.0.255 10.0.0.10:1024-65535]"},
+	}
+	for _, test := range cases {
+		a, b := mustParseEndpoint(test.a), mustParseEndpoint(test.b)
+		if result := fmt.Sprint(a.Subtract(b)); test.result != result {
+			t.Errorf("expecting %v, got %v when subtracting %v from %v", test.result, result, b, a)
+		}
+	}
+}
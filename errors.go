@@ -0,0 +1,55 @@
+// SPDX-License-Identifier: MIT-0
+
+package iputils
+
+import (
+	"errors"
+)
+
+// Errors returned by the package are wrapping one of the following errors,
+// so the kind of the problem can be checked with errors.Is.
+var (
+	// ErrInvalidIP means that an address is malformed.
+	ErrInvalidIP = errors.New("invalid IP address")
+
+	// ErrInvalidNetwork means that a network is missing, malformed or has a
+	// non-contiguous mask.
+	ErrInvalidNetwork = errors.New("invalid network")
+
+	// ErrInvalidMask means that a mask has a wrong size or is not
+	// contiguous where a contiguous one is required.
+	ErrInvalidMask = errors.New("invalid mask")
+
+	// ErrInvalidRange means that a range of addresses is malformed.
+	ErrInvalidRange = errors.New("invalid IP range")
+
+	// ErrInvalidEndpoint means that an endpoint or a port range is
+	// malformed.
+	ErrInvalidEndpoint = errors.New("invalid endpoint")
+
+	// ErrInvalidEncoding means that serialized data (an iterator state, an
+	// encoded set, a sortable key) can't be decoded.
+	ErrInvalidEncoding = errors.New("invalid encoding")
+
+	// ErrFamilyMismatch means that IPv4 and IPv6 values were mixed where a
+	// single family is required.
+	ErrFamilyMismatch = errors.New("address family mismatch")
+
+	// ErrOverflow means that a result doesn't fit into the address space.
+	ErrOverflow = errors.New("address space overflow")
+
+	// ErrOutOfRange means that an argument (an index, a prefix length, an
+	// address) is outside of the allowed bounds.
+	ErrOutOfRange = errors.New("value out of range")
+
+	// ErrConflict means that a network overlaps with networks already stored
+	// and the insertion policy doesn't allow that.
+	ErrConflict = errors.New("conflicting network")
+
+	// ErrNoAddresses means that a host name resolved to no usable
+	// addresses.
+	ErrNoAddresses = errors.New("no addresses")
+
+	// ErrPoolExhausted means that there are no free addresses left.
+	ErrPoolExhausted = errors.New("no free addresses")
+)
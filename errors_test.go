@@ -0,0 +1,89 @@
+// SPDX-License-Identifier: MIT-0
+
+package iputils
+
+import (
+	"errors"
+	"math/big"
+	"net"
+	"net/netip"
+	"testing"
+)
+
+func TestErrorKinds(t *testing.T) {
+	type testCase struct {
+		name string
+		call func() error
+		kind error
+	}
+	errorOf := func(_ interface{}, err error) error { return err }
+	nonContiguous := &net.IPNet{IP: net.IP{10, 0, 0, 0}, Mask: net.IPMask{255, 0, 255, 0}}
+	cases := []testCase{
+		testCase{"To4Strict IPv6", func() error { return errorOf(To4Strict(net.ParseIP("2001:db8::1"))) }, ErrFamilyMismatch},
+		testCase{"To4Strict invalid", func() error { return errorOf(To4Strict(net.IP{1, 2, 3})) }, ErrInvalidIP},
+		testCase{"CompareIPs", func() error { return errorOf(CompareIPs(net.IP{1, 2, 3, 4}, net.ParseIP("::1"))) }, ErrFamilyMismatch},
+		testCase{"AddWithinNetwork", func() error {
+			return errorOf(AddWithinNetwork(mustParseCIDR("10.0.0.0/24"), net.IP{10, 0, 1, 0}, 1))
+		}, ErrOutOfRange},
+		testCase{"ExpandCIDR", func() error { return errorOf(ExpandCIDR(nonContiguous, 10)) }, ErrInvalidNetwork},
+		testCase{"ResumeIPRangeIterator", func() error { return errorOf(ResumeIPRangeIterator([]byte{1, 5})) }, ErrInvalidEncoding},
+		testCase{"ParseIPRange", func() error { return errorOf(ParseIPRange("10.0.0.1-::1")) }, ErrInvalidRange},
+		testCase{"ParseIPRange family", func() error { return errorOf(ParseIPRange("10.0.0.1-::1")) }, ErrFamilyMismatch},
+		testCase{"ParseAny IP", func() error { return errorOf(ParseAny("10.0.0")) }, ErrInvalidIP},
+		testCase{"ParseAny network", func() error { return errorOf(ParseAny("10.0.0.0/33")) }, ErrInvalidNetwork},
+		testCase{"ShiftPrefix", func() error {
+			return errorOf(ShiftPrefix(mustParseCIDR("255.255.255.0/24"), big.NewInt(1)))
+		}, ErrOverflow},
+		testCase{"MaskToPrefix", func() error { return errorOf(MaskToPrefix(nonContiguous.Mask)) }, ErrInvalidMask},
+		testCase{"ToPrefix", func() error { return errorOf(ToPrefix(nil)) }, ErrInvalidNetwork},
+		testCase{"FromPrefixes", func() error { return errorOf(FromPrefixes(make([]netip.Prefix, 1))) }, ErrInvalidNetwork},
+		testCase{"ParseIPv6", func() error { return errorOf(ParseIPv6("::ffff:10.0.0.1")) }, ErrFamilyMismatch},
+		testCase{"ParseSortableKey", func() error { return errorOf(ParseSortableKey("xyz")) }, ErrInvalidEncoding},
+		testCase{"ParseEndpoint", func() error { return errorOf(ParseEndpoint("10.0.0.1:99999")) }, ErrInvalidEndpoint},
+		testCase{"ParseEndpoint range", func() error { return errorOf(ParseEndpoint("10.0.0.2-10.0.0.1")) }, ErrInvalidRange},
+		testCase{"DelegationAt", func() error {
+			return errorOf(DelegationAt(mustParseCIDR("10.0.0.0/24"), 26, 4, DelegateRightmost))
+		}, ErrOutOfRange},
+		testCase{"DeriveFree", func() error {
+			return errorOf(DeriveFree(mustParseCIDR("10.0.0.0/30"), nil, func(net.IP) bool { return true }))
+		}, ErrPoolExhausted},
+		testCase{"InterfaceID", func() error { return errorOf(InterfaceID(net.ParseIP("::1"), 129)) }, ErrOutOfRange},
+		testCase{"DecodeIPSet", func() error { return errorOf(DecodeIPSet([]byte("IPS"))) }, ErrInvalidEncoding},
+		testCase{"TaggedPrefixes.Add", func() error { return new(TaggedPrefixes).Add(nil, nil) }, ErrInvalidNetwork},
+	}
+	for _, test := range cases {
+		err := test.call()
+		if !errors.Is(err, test.kind) {
+			t.Errorf("%v: expecting an error of kind %q, got %v", test.name, test.kind, err)
+		}
+	}
+}
+
+func TestErrorMessages(t *testing.T) {
+	type testCase struct {
+		err     error
+		message string
+	}
+	errorOf := func(_ interface{}, err error) error { return err }
+	cases := []testCase{
+		testCase{errorOf(ParseEndpoint("10.0.0.1:99999")), `invalid endpoint: port range "99999" in "10.0.0.1:99999"`},
+		testCase{errorOf(ParseEndpoint("10.0.0.1:20-10")), `invalid endpoint: port range "20-10" starts after its end in "10.0.0.1:20-10"`},
+		testCase{errorOf(ParseEndpoint("my-host:80")), `invalid IP range "my-host" in "my-host:80"`},
+		testCase{errorOf(ParseSortableKey("xyz")), `invalid encoding: sortable key "xyz"`},
+		testCase{errorOf(ParseIPv4("10.0.0")), `invalid IP address: IPv4 address "10.0.0"`},
+		testCase{errorOf(ParseIPv6("10.0.0.1")), `invalid IP address: IPv6 address "10.0.0.1"`},
+	}
+	for _, test := range cases {
+		if test.err == nil || test.err.Error() != test.message {
+			t.Errorf("expecting %q, got %v", test.message, test.err)
+		}
+	}
+}
+
+func TestResolverErrorIsWrapped(t *testing.T) {
+	backendErr := errors.New("backend is down")
+	r := &ChainResolver{Backends: []Resolver{ResolverFunc(func(net.IP) (Tags, error) { return nil, backendErr })}}
+	if _, err := r.Lookup(net.ParseIP("10.0.0.1")); !errors.Is(err, backendErr) {
+		t.Errorf("expecting the backend error to be wrapped, got %v", err)
+	}
+}
@@ -0,0 +1,117 @@
+// SPDX-License-Identifier: MIT-0
+
+package iputils
+
+import (
+	"net"
+	"net/netip"
+	"sort"
+)
+
+// GroupByPrefix groups addresses by the network with the given prefix length
+// containing them.  The prefix length is counted in the address space of
+// every address family separately; IPv4 addresses are grouped into IPv4
+// prefixes regardless of their form.  Addresses which are not valid or for
+// which the prefix length is out of their address space are skipped.
+func GroupByPrefix(ips []net.IP, prefixLen int) map[netip.Prefix][]net.IP {
+	result := map[netip.Prefix][]net.IP{}
+	for _, ip := range ips {
+		addr, ok := netip.AddrFromSlice(ip)
+		if !ok {
+			continue
+		}
+		prefix, err := addr.Unmap().Prefix(prefixLen)
+		if err != nil {
+			continue
+		}
+		result[prefix] = append(result[prefix], ip)
+	}
+	return result
+}
+
+// GroupByNetworks groups addresses by the most specific of the networks
+// containing them.  As with GroupByPrefix, addresses are matched only
+// against networks of their own family, so an IPv4 address (in any form)
+// never falls into an IPv6 network such as ::/0.  Addresses not contained
+// in any of the networks are skipped.  The networks are put into a prefix trie first, so every address
+// is matched in time proportional to the address length rather than to the
+// number of networks.  Networks which can't be converted with ToPrefix are
+// ignored.
+func GroupByNetworks(ips []net.IP, networks []*net.IPNet) map[netip.Prefix][]net.IP {
+	var trie prefixTrie
+	for _, n := range networks {
+		prefix, err := ToPrefix(n)
+		if err != nil {
+			continue
+		}
+		if node, err := trie.insert(n); err == nil {
+			node.value = prefix
+		}
+	}
+	result := map[netip.Prefix][]net.IP{}
+	for _, ip := range ips {
+		matches := trie.matches(ip)
+		if len(matches) == 0 {
+			continue
+		}
+		prefix := matches[len(matches)-1].value.(netip.Prefix)
+		result[prefix] = append(result[prefix], ip)
+	}
+	return result
+}
+
+// ClusterIPs summarizes addresses as a sorted list of networks.  Addresses
+// are grouped by their network with the prefix length maxPrefixLen (as in
+// GroupByPrefix), and every group is narrowed down to the longest prefix
+// still covering all its addresses.  So the result has one network per
+// group and no network is shorter than maxPrefixLen.
+//
+// The prefix length is clamped to the address length of every family, so
+// with e.g. 48 IPv4 addresses are clustered into /32 networks rather than
+// dropped.  Only invalid addresses are skipped.
+func ClusterIPs(ips []net.IP, maxPrefixLen int) []*net.IPNet {
+	v4, v6 := SplitByFamily(ips)
+	groups := GroupByPrefix(v4, clampPrefixLen(maxPrefixLen, IPv4Size*8))
+	for prefix, group := range GroupByPrefix(v6, clampPrefixLen(maxPrefixLen, IPv6Size*8)) {
+		groups[prefix] = group
+	}
+
+	var prefixes []netip.Prefix
+	for prefix, group := range groups {
+		low, high := prefix.Addr(), prefix.Addr()
+		for i, ip := range group {
+			addr, _ := netip.AddrFromSlice(ip)
+			addr = addr.Unmap()
+			if i == 0 || addr.Less(low) {
+				low = addr
+			}
+			if i == 0 || high.Less(addr) {
+				high = addr
+			}
+		}
+		for bits := low.BitLen(); bits > prefix.Bits(); bits-- {
+			if covering, _ := low.Prefix(bits); covering.Contains(high) {
+				prefix = covering
+				break
+			}
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Slice(prefixes, func(i, j int) bool { return prefixes[i].Addr().Less(prefixes[j].Addr()) })
+	result := make([]*net.IPNet, len(prefixes))
+	for i, prefix := range prefixes {
+		result[i] = FromPrefix(prefix)
+	}
+	return result
+}
+
+// clampPrefixLen limits the prefix length to the range from 0 to bits
+func clampPrefixLen(prefixLen, bits int) int {
+	switch {
+	case prefixLen < 0:
+		return 0
+	case prefixLen > bits:
+		return bits
+	}
+	return prefixLen
+}
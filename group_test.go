@@ -0,0 +1,123 @@
+// SPDX-License-Identifier: MIT-0
+
+package iputils
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"sort"
+	"testing"
+)
+
+// formatGroups formats groups sorted by prefix
+func formatGroups(groups map[netip.Prefix][]net.IP) string {
+	var prefixes []netip.Prefix
+	for p := range groups {
+		prefixes = append(prefixes, p)
+	}
+	sort.Slice(prefixes, func(i, j int) bool {
+		return prefixes[i].Addr().Less(prefixes[j].Addr()) ||
+			prefixes[i].Addr() == prefixes[j].Addr() && prefixes[i].Bits() < prefixes[j].Bits()
+	})
+	result := ""
+	for _, p := range prefixes {
+		result += fmt.Sprintf("%v%v ", p, groups[p])
+	}
+	return result
+}
+
+func TestGroupByPrefix(t *testing.T) {
+	ips := []net.IP{
+		net.ParseIP("10.0.0.1"),
+		net.IP{10, 0, 1, 2},
+		net.ParseIP("10.0.0.200"),
+		net.ParseIP("::ffff:10.0.1.3"),
+		net.ParseIP("2001:db8::1"),
+		net.IP{1, 2, 3},
+	}
+	type testCase struct {
+		prefixLen int
+		result    string
+	}
+	cases := []testCase{
+		testCase{24, "10.0.0.0/24[10.0.0.1 10.0.0.200] 10.0.1.0/24[10.0.1.2 10.0.1.3] 2001:d00::/24[2001:db8::1] "},
+		testCase{16, "10.0.0.0/16[10.0.0.1 10.0.1.2 10.0.0.200 10.0.1.3] 2001::/16[2001:db8::1] "},
+		testCase{64, "2001:db8::/64[2001:db8::1] "},
+		testCase{-1, ""},
+	}
+	for _, test := range cases {
+		if result := formatGroups(GroupByPrefix(ips, test.prefixLen)); test.result != result {
+			t.Errorf("expecting %v, got %v for /%v", test.result, result, test.prefixLen)
+		}
+	}
+}
+
+func TestGroupByNetworks(t *testing.T) {
+	ips := []net.IP{
+		net.ParseIP("10.0.0.1"),
+		net.IP{10, 0, 1, 2},
+		net.ParseIP("10.20.0.1"),
+		net.ParseIP("192.168.0.1"),
+		net.ParseIP("2001:db8::1"),
+		net.ParseIP("2001:db9::1"),
+	}
+	networks := []*net.IPNet{
+		mustParseCIDR("10.0.0.0/8"),
+		mustParseCIDR("10.0.1.0/24"),
+		mustParseCIDR("2001:db8::/32"),
+		&net.IPNet{IP: net.IP{192, 168, 0, 0}, Mask: net.IPMask{255, 0, 255, 0}},
+	}
+	expected := "10.0.0.0/8[10.0.0.1 10.20.0.1] 10.0.1.0/24[10.0.1.2] 2001:db8::/32[2001:db8::1] "
+	if result := formatGroups(GroupByNetworks(ips, networks)); expected != result {
+		t.Errorf("expecting %v, got %v", expected, result)
+	}
+	// IPv4 addresses are not grouped under IPv6 networks
+	ips = []net.IP{net.ParseIP("8.8.8.8"), net.ParseIP("::ffff:10.0.0.1"), net.ParseIP("2001:db8::1")}
+	networks = []*net.IPNet{mustParseCIDR("::/0"), mustParseCIDR("10.0.0.0/8")}
+	expected = "10.0.0.0/8[10.0.0.1] ::/0[2001:db8::1] "
+	if result := formatGroups(GroupByNetworks(ips, networks)); expected != result {
+		t.Errorf("expecting %v, got %v", expected, result)
+	}
+}
+
+func TestClusterIPs(t *testing.T) {
+	ips := []net.IP{
+		net.ParseIP("203.0.113.7"),
+		net.ParseIP("203.0.113.9"),
+		net.ParseIP("203.0.113.200"),
+		net.ParseIP("198.51.100.1"),
+		net.IP{198, 51, 100, 3},
+		net.ParseIP("192.0.2.55"),
+		net.ParseIP("::ffff:192.0.2.55"),
+		net.ParseIP("2001:db8::1"),
+		net.ParseIP("2001:db8::ff"),
+		net.IP{1, 2, 3},
+	}
+	type testCase struct {
+		maxPrefixLen int
+		result       string
+	}
+	cases := []testCase{
+		testCase{24, "[192.0.2.55/32 198.51.100.0/30 203.0.113.0/24 2001:db8::/120]"},
+		testCase{28, "[192.0.2.55/32 198.51.100.0/30 203.0.113.0/28 203.0.113.200/32 2001:db8::/120]"},
+		testCase{16, "[192.0.2.55/32 198.51.100.0/30 203.0.113.0/24 2001:db8::/120]"},
+		testCase{0, "[192.0.0.0/4 2001:db8::/120]"},
+		testCase{33, "[192.0.2.55/32 198.51.100.1/32 198.51.100.3/32 203.0.113.7/32 203.0.113.9/32 203.0.113.200/32 2001:db8::/120]"},
+		testCase{128, "[192.0.2.55/32 198.51.100.1/32 198.51.100.3/32 203.0.113.7/32 203.0.113.9/32 203.0.113.200/32 2001:db8::1/128 2001:db8::ff/128]"},
+		testCase{-1, "[192.0.0.0/4 2001:db8::/120]"},
+	}
+	for _, test := range cases {
+		if result := fmt.Sprint(ClusterIPs(ips, test.maxPrefixLen)); result != test.result {
+			t.Errorf("expecting %v, got %v for /%v", test.result, result, test.maxPrefixLen)
+		}
+	}
+	// no family is lost when the prefix length only fits IPv6
+	mixed := []net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.9"), net.ParseIP("2001:db8::1")}
+	if result := fmt.Sprint(ClusterIPs(mixed, 48)); result != "[10.0.0.1/32 10.0.0.9/32 2001:db8::1/128]" {
+		t.Errorf("unexpected clusters %v for mixed families", result)
+	}
+	if result := ClusterIPs(nil, 24); len(result) != 0 {
+		t.Errorf("expecting nothing for no addresses, got %v", result)
+	}
+}
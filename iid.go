@@ -0,0 +1,45 @@
+// SPDX-License-Identifier: MIT-0
+
+package iputils
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+)
+
+// InterfaceID returns the value of the bits of ip following the first
+// prefixLen bits.  The boundary may be at any bit, not only at /64, so
+// fields of an addressing plan (VLAN, rack, host numbers, ...) can be
+// extracted by choosing the boundary and shifting the result.
+//
+// IPv4 addresses are 32 bits long, everything else is 128 bits long.
+func InterfaceID(ip net.IP, prefixLen int) (*big.Int, error) {
+	normalized := normalizeRange(IPRange{ip, ip}).First
+	if normalized == nil {
+		return nil, fmt.Errorf("%w %v", ErrInvalidIP, ip)
+	}
+	ip = normalized
+	bits := len(ip) * 8
+	if prefixLen < 0 || prefixLen > bits {
+		return nil, fmt.Errorf("%w: prefix length %v for %v", ErrOutOfRange, prefixLen, ip)
+	}
+	result := ipToInt(ip)
+	mask := new(big.Int).Lsh(big.NewInt(1), uint(bits-prefixLen))
+	return result.Mod(result, mask), nil
+}
+
+// SetInterfaceID returns the address of the network with the host bits set
+// to iid.  It is the reverse of InterfaceID: the address is split at the
+// prefix length of the network.  An error is returned if iid is negative
+// or doesn't fit into the host bits.
+func SetInterfaceID(n *net.IPNet, iid *big.Int) (net.IP, error) {
+	first, size, err := networkFirstAndSize(n)
+	if err != nil {
+		return nil, err
+	}
+	if iid == nil || iid.Sign() < 0 || iid.Cmp(size) >= 0 {
+		return nil, fmt.Errorf("%w: interface id %v doesn't fit into network %v", ErrOutOfRange, iid, n)
+	}
+	return intToIP(new(big.Int).Add(ipToInt(first), iid), len(first)), nil
+}
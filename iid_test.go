@@ -0,0 +1,119 @@
+// SPDX-License-Identifier: MIT-0
+
+package iputils
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+	"testing"
+)
+
+func TestInterfaceID(t *testing.T) {
+	type testCase struct {
+		ip        string
+		prefixLen int
+		result    string
+	}
+	cases := []testCase{
+		testCase{"2001:db8::1", 64, "1"},
+		testCase{"2001:db8::1:2:3:4", 64, "281483566841860"},
+		testCase{"2001:db8:0:12::", 56, "332041393326771929088"},
+		testCase{"2001:db8::1", 128, "0"},
+		testCase{"::1", 0, "1"},
+		testCase{"ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff", 0, "340282366920938463463374607431768211455"},
+		testCase{"192.0.2.77", 24, "77"},
+		testCase{"::ffff:192.0.2.77", 28, "13"},
+		testCase{"10.1.2.3", 0, "167838211"},
+	}
+	for _, test := range cases {
+		iid, err := InterfaceID(net.ParseIP(test.ip), test.prefixLen)
+		if err != nil {
+			t.Errorf("unexpected error %v for %v/%v", err, test.ip, test.prefixLen)
+			continue
+		}
+		if iid.String() != test.result {
+			t.Errorf("expecting %v, got %v for %v/%v", test.result, iid, test.ip, test.prefixLen)
+		}
+	}
+}
+
+func TestInterfaceIDFaults(t *testing.T) {
+	type faultCase struct {
+		ip        net.IP
+		prefixLen int
+	}
+	faultCases := []faultCase{
+		faultCase{nil, 0},
+		faultCase{net.IP{1, 2, 3}, 0},
+		faultCase{net.ParseIP("2001:db8::"), -1},
+		faultCase{net.ParseIP("2001:db8::"), 129},
+		faultCase{net.ParseIP("192.0.2.1"), 33},
+	}
+	for _, test := range faultCases {
+		if iid, err := InterfaceID(test.ip, test.prefixLen); err == nil {
+			t.Errorf("didn't get an error for %v/%v, got %v", test.ip, test.prefixLen, iid)
+		}
+	}
+}
+
+func TestSetInterfaceID(t *testing.T) {
+	type testCase struct {
+		network string
+		iid     int64
+		result  string
+	}
+	cases := []testCase{
+		testCase{"2001:db8::/64", 1, "2001:db8::1"},
+		testCase{"2001:db8:0:1::5/64", 0x10002, "2001:db8:0:1::1:2"},
+		testCase{"2001:db8::/120", 0xab, "2001:db8::ab"},
+		testCase{"2001:db8::1/128", 0, "2001:db8::1"},
+		testCase{"192.0.2.0/24", 77, "192.0.2.77"},
+	}
+	for _, test := range cases {
+		ip, err := SetInterfaceID(mustParseCIDR(test.network), big.NewInt(test.iid))
+		if err != nil {
+			t.Errorf("unexpected error %v for %v and %v", err, test.network, test.iid)
+			continue
+		}
+		if ip.String() != test.result {
+			t.Errorf("expecting %v, got %v for %v and %v", test.result, ip, test.network, test.iid)
+		}
+	}
+}
+
+func TestSetInterfaceIDFaults(t *testing.T) {
+	type faultCase struct {
+		network *net.IPNet
+		iid     *big.Int
+	}
+	faultCases := []faultCase{
+		faultCase{nil, big.NewInt(0)},
+		faultCase{mustParseCIDR("2001:db8::/64"), nil},
+		faultCase{mustParseCIDR("2001:db8::/64"), big.NewInt(-1)},
+		faultCase{mustParseCIDR("2001:db8::/120"), big.NewInt(256)},
+		faultCase{mustParseCIDR("192.0.2.0/31"), big.NewInt(2)},
+		faultCase{&net.IPNet{IP: net.ParseIP("2001:db8::"), Mask: net.IPMask{255, 0, 255, 0}}, big.NewInt(0)},
+	}
+	for _, test := range faultCases {
+		if ip, err := SetInterfaceID(test.network, test.iid); err == nil {
+			t.Errorf("didn't get an error for %v and %v, got %v", test.network, test.iid, ip)
+		}
+	}
+}
+
+func ExampleInterfaceID() {
+	// the plan encodes the rack number in bits 48-55 of the address
+	ip := net.ParseIP("2001:db8:0:2a07::10")
+	field, _ := InterfaceID(ip, 48)
+	rack := new(big.Int).Rsh(field, 128-56)
+	fmt.Println(rack)
+
+	_, network, _ := net.ParseCIDR("2001:db8:0:2a00::/56")
+	host, _ := SetInterfaceID(network, big.NewInt(0x10))
+	fmt.Println(host)
+
+	// Output:
+	// 42
+	// 2001:db8:0:2a00::10
+}
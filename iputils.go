@@ -4,7 +4,9 @@ package iputils
 
 import (
 	"bytes"
+	"encoding"
 	"fmt"
+	"math/big"
 	"net"
 )
 
@@ -47,6 +49,38 @@ func CopyIP(ip net.IP) net.IP {
 	return result
 }
 
+// IsIPv4Mapped reports whether ip is an IPv4 address in the IPv4-mapped
+// IPv6 form, i.e. 16 bytes starting with V4InV6Prefix.  IPv4 addresses in
+// the 4-byte form are not IPv4-mapped.
+func IsIPv4Mapped(ip net.IP) bool {
+	return len(ip) == IPv6Size && bytes.HasPrefix(ip, V4InV6Prefix)
+}
+
+// To16Canonical returns a copy of ip in the 16-byte form: IPv4 addresses are
+// converted to the IPv4-mapped IPv6 form.  If ip is not valid, nil is
+// returned.
+func To16Canonical(ip net.IP) net.IP {
+	if ip16 := ip.To16(); ip16 != nil {
+		return CopyIP(ip16)
+	}
+	return nil
+}
+
+// To4Strict returns a copy of ip in the 4-byte form.  Unlike net.IP.To4, it
+// returns an error describing the problem if ip is not an IPv4 address
+// (in the 4-byte or IPv4-mapped form).
+func To4Strict(ip net.IP) (net.IP, error) {
+	switch {
+	case len(ip) == IPv4Size:
+		return CopyIP(ip), nil
+	case IsIPv4Mapped(ip):
+		return CopyIP(ip[len(V4InV6Prefix):]), nil
+	case len(ip) == IPv6Size:
+		return nil, fmt.Errorf("%w: IPv6 address %v is not representable as IPv4 address", ErrFamilyMismatch, ip)
+	}
+	return nil, fmt.Errorf("%w %v", ErrInvalidIP, ip)
+}
+
 // Next increments ip to the next sequental value if that's possible.
 // If not possible, false is returned.
 func Next(ip net.IP) bool {
@@ -105,6 +139,50 @@ func GetNetworkIPRange(n *net.IPNet) (first, last net.IP) {
 	return
 }
 
+// AddWithinNetwork adds offset to the host part of ip modulo the size of the
+// network, so the result never leaves the network.  Negative offsets move
+// backwards.  An error is returned if ip doesn't belong to the network.
+func AddWithinNetwork(n *net.IPNet, ip net.IP, offset int64) (net.IP, error) {
+	first, size, err := networkFirstAndSize(n)
+	if err != nil {
+		return nil, err
+	}
+	if !n.Contains(ip) {
+		return nil, fmt.Errorf("%w: IP address %v doesn't belong to network %v", ErrOutOfRange, ip, n)
+	}
+	start := ipToInt(first)
+	value := ipToInt(ip.To16())
+	if len(first) == IPv4Size {
+		value = ipToInt(ip.To4())
+	}
+	value.Sub(value, start)
+	value.Add(value, big.NewInt(offset))
+	value.Mod(value, size)
+	return intToIP(value.Add(value, start), len(first)), nil
+}
+
+// ExpandCIDR returns every address of the network as a string.  If the
+// network contains more than max addresses, an error is returned instead.
+func ExpandCIDR(n *net.IPNet, max int) ([]string, error) {
+	ones, bits := n.Mask.Size()
+	if ones == 0 && bits == 0 {
+		return nil, fmt.Errorf("%w: network %v has non-contiguous mask", ErrInvalidNetwork, n)
+	}
+	hostBits := uint(bits - ones)
+	if hostBits >= 62 || max < 0 || 1<<hostBits > int64(max) {
+		return nil, fmt.Errorf("%w: network %v contains more than %v addresses", ErrOutOfRange, n, max)
+	}
+	result := make([]string, 0, 1<<hostBits)
+	iter, err := NewIPRangeIterator(GetNetworkIPRange(n))
+	if err != nil {
+		return nil, err
+	}
+	for ip, ok := iter.Next(); ok; ip, ok = iter.Next() {
+		result = append(result, ip.String())
+	}
+	return result, nil
+}
+
 // CompareIPs compares two ip addresses and returns 0 if they are equal,
 // -1 if the first one preceeds the second, +1 if the first one is bigger that
 // the second.
@@ -112,11 +190,33 @@ func GetNetworkIPRange(n *net.IPNet) (first, last net.IP) {
 // If ip addresses has different sizes, an error is returned.
 func CompareIPs(a, b net.IP) (int, error) {
 	if len(a) != len(b) {
-		return 0, fmt.Errorf("IP addresses %v and %v have different sizes", a, b)
+		return 0, fmt.Errorf("%w: IP addresses %v and %v have different sizes", ErrFamilyMismatch, a, b)
 	}
 	return bytes.Compare(a, b), nil
 }
 
+// Compare compares two ip addresses and returns 0 if they are equal,
+// -1 if the first one preceeds the second, +1 if the first one is bigger that
+// the second.
+//
+// Unlike CompareIPs, Compare defines a total order over all addresses: both
+// addresses are converted to the 16-byte form first, so an IPv4 address sorts
+// as its IPv4-mapped IPv6 equivalent.  Values which are not valid IP addresses
+// sort before all valid ones.  This makes Compare suitable for use directly
+// in sort.Slice and slices.SortFunc.
+func Compare(a, b net.IP) int {
+	a16, b16 := a.To16(), b.To16()
+	switch {
+	case a16 != nil && b16 != nil:
+		return bytes.Compare(a16, b16)
+	case a16 != nil:
+		return 1
+	case b16 != nil:
+		return -1
+	}
+	return bytes.Compare(a, b)
+}
+
 // IPRangeIterator allows you to iterate over a range of IP addresses
 type IPRangeIterator interface {
 
@@ -127,29 +227,133 @@ type IPRangeIterator interface {
 
 // GetIPRangeIterator returns an interator over IP range.  The last ip will be included
 // into the sequence produced.
+//
+// The range is not checked: the iteration stops at the first address Next
+// can't step over, e.g. ::ffff:255.255.255.255 of a 16-byte range crossing
+// the end of the IPv4-mapped block.  Use NewIPRangeIterator to get an error
+// for such ranges instead.
+//
+// The returned iterator implements encoding.BinaryMarshaler, so its progress
+// can be saved and the iteration continued later with ResumeIPRangeIterator.
 func GetIPRangeIterator(first, last net.IP) IPRangeIterator {
-	return &ipRangeIterator{first, last, CopyIP(first)}
+	return &ipRangeIterator{first: first, last: last, next: CopyIP(first)}
+}
+
+// NewIPRangeIterator is GetIPRangeIterator which fails for ranges the
+// iterator can't walk to the end: addresses of different sizes and 16-byte
+// ranges crossing the end of the IPv4-mapped block.  A range with first
+// bigger than last is empty, not an error.
+func NewIPRangeIterator(first, last net.IP) (IPRangeIterator, error) {
+	if err := checkIteratorRange(first, last); err != nil {
+		return nil, err
+	}
+	return GetIPRangeIterator(first, last), nil
+}
+
+// checkIteratorRange reports an error if Next can't walk from first to last
+func checkIteratorRange(first, last net.IP) error {
+	if len(first) != len(last) || (len(first) != IPv4Size && len(first) != IPv6Size) {
+		return fmt.Errorf("%w: IP addresses %v and %v have different or invalid sizes", ErrFamilyMismatch, first, last)
+	}
+	if len(first) == IPv6Size && bytes.Compare(first, MaxIPv4In6) <= 0 && bytes.Compare(last, MaxIPv4In6) > 0 {
+		return fmt.Errorf("%w: %v-%v crosses the end of the IPv4-mapped block", ErrInvalidRange, first, last)
+	}
+	return nil
+}
+
+// ResumeIPRangeIterator restores an iterator from the state produced by its
+// MarshalBinary method.  The restored iterator continues exactly from the
+// address that would have been returned next by the original one.
+func ResumeIPRangeIterator(state []byte) (IPRangeIterator, error) {
+	iter := &ipRangeIterator{}
+	if err := iter.UnmarshalBinary(state); err != nil {
+		return nil, err
+	}
+	return iter, nil
 }
 
 type ipRangeIterator struct {
 	first net.IP
 	last  net.IP
 	next  net.IP
+
+	// done is set when the last address of the address space was produced
+	done bool
 }
 
 func (iter *ipRangeIterator) Next() (ip net.IP, ok bool) {
 	result := CopyIP(iter.next)
 	check, err := CompareIPs(iter.next, iter.last)
-	if err == nil && check <= 0 {
-		Next(iter.next)
+	if err == nil && check <= 0 && !iter.done {
+		iter.done = !Next(iter.next)
 		return result, true
 	}
 	return result, false
 }
 
+// unread makes ip, the address just produced, the next one again
+func (iter *ipRangeIterator) unread(ip net.IP) {
+	iter.next = CopyIP(ip)
+	iter.done = false
+}
+
 func (iter *ipRangeIterator) String() string {
-	if res, _ := CompareIPs(iter.last, iter.next); res < 0 {
+	if res, _ := CompareIPs(iter.last, iter.next); res < 0 || iter.done {
 		return fmt.Sprintf("IPRangeIterator(%v -> %v, next: none)", iter.first, iter.last)
 	}
 	return fmt.Sprintf("IPRangeIterator(%v -> %v, next: %v)", iter.first, iter.last, iter.next)
 }
+
+// ipRangeIteratorStateVersion is the version of the ipRangeIterator binary state
+const ipRangeIteratorStateVersion = 1
+
+var (
+	_ encoding.BinaryMarshaler   = (*ipRangeIterator)(nil)
+	_ encoding.BinaryUnmarshaler = (*ipRangeIterator)(nil)
+)
+
+// MarshalBinary encodes the iterator state: the version byte, the size of
+// addresses, the done flag and then the first, the last and the next
+// addresses.
+func (iter *ipRangeIterator) MarshalBinary() ([]byte, error) {
+	size := len(iter.first)
+	if size == 0 || size != len(iter.last) || size != len(iter.next) {
+		return nil, fmt.Errorf("%w: IP addresses %v and %v have different sizes", ErrFamilyMismatch, iter.first, iter.last)
+	}
+	var done byte
+	if iter.done {
+		done = 1
+	}
+	state := make([]byte, 0, 3+3*size)
+	state = append(state, ipRangeIteratorStateVersion, byte(size), done)
+	state = append(state, iter.first...)
+	state = append(state, iter.last...)
+	state = append(state, iter.next...)
+	return state, nil
+}
+
+// UnmarshalBinary restores the iterator state produced by MarshalBinary.
+func (iter *ipRangeIterator) UnmarshalBinary(state []byte) error {
+	if len(state) < 2 || state[0] != ipRangeIteratorStateVersion {
+		return fmt.Errorf("%w: unsupported IPRangeIterator state", ErrInvalidEncoding)
+	}
+	size := int(state[1])
+	if size != IPv4Size && size != IPv6Size {
+		return fmt.Errorf("%w: IPRangeIterator state has unexpected ip size %v", ErrInvalidEncoding, size)
+	}
+	if len(state) != 3+3*size {
+		return fmt.Errorf("%w: IPRangeIterator state has unexpected length %v", ErrInvalidEncoding, len(state))
+	}
+	if state[2] > 1 {
+		return fmt.Errorf("%w: IPRangeIterator state has unexpected done flag %v", ErrInvalidEncoding, state[2])
+	}
+	data := state[3:]
+	if err := checkIteratorRange(data[:size], data[size:2*size]); err != nil {
+		return fmt.Errorf("%w: IPRangeIterator state has a range it can't walk: %w", ErrInvalidEncoding, err)
+	}
+	iter.done = state[2] == 1
+	iter.first = CopyIP(data[:size])
+	iter.last = CopyIP(data[size : 2*size])
+	iter.next = CopyIP(data[2*size:])
+	return nil
+}
@@ -3,11 +3,54 @@
 package iputils
 
 import (
+	"bytes"
+	"encoding"
+	"errors"
 	"fmt"
 	"net"
+	"sort"
 	"testing"
 )
 
+func TestIPv4MappedConversions(t *testing.T) {
+	type testCase struct {
+		ip     net.IP
+		mapped bool
+		to16   net.IP
+		to4    net.IP
+	}
+	cases := []testCase{
+		testCase{net.IP{192, 0, 2, 1}, false,
+			net.IP{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0xff, 0xff, 192, 0, 2, 1}, net.IP{192, 0, 2, 1}},
+		testCase{net.ParseIP("192.0.2.1"), true,
+			net.IP{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0xff, 0xff, 192, 0, 2, 1}, net.IP{192, 0, 2, 1}},
+		testCase{net.ParseIP("::192.0.2.1"), false, net.ParseIP("::192.0.2.1"), nil},
+		testCase{net.ParseIP("2001:db8::1"), false, net.ParseIP("2001:db8::1"), nil},
+		testCase{net.IP{1, 2, 3}, false, nil, nil},
+		testCase{nil, false, nil, nil},
+	}
+	for _, test := range cases {
+		if mapped := IsIPv4Mapped(test.ip); test.mapped != mapped {
+			t.Errorf("expecting IsIPv4Mapped %v, got %v for %v", test.mapped, mapped, []byte(test.ip))
+		}
+		if to16 := To16Canonical(test.ip); !bytes.Equal(test.to16, to16) {
+			t.Errorf("expecting To16Canonical %v, got %v for %v", []byte(test.to16), []byte(to16), []byte(test.ip))
+		}
+		to4, err := To4Strict(test.ip)
+		if !bytes.Equal(test.to4, to4) || (err == nil) != (test.to4 != nil) {
+			t.Errorf("expecting To4Strict %v, got (%v, %v) for %v", []byte(test.to4), []byte(to4), err, []byte(test.ip))
+		}
+	}
+
+	ip := net.ParseIP("192.0.2.1")
+	to4, _ := To4Strict(ip)
+	to4[0] = 10
+	To16Canonical(ip)[15] = 10
+	if !ip.Equal(net.ParseIP("192.0.2.1")) {
+		t.Errorf("conversions modified the original address: %v", ip)
+	}
+}
+
 func TestNext(t *testing.T) {
 	type testCase struct {
 		input net.IP
@@ -90,6 +133,98 @@ func TestGetIPRange(t *testing.T) {
 	}
 }
 
+func TestAddWithinNetwork(t *testing.T) {
+	type testCase struct {
+		network string
+		ip      net.IP
+		offset  int64
+		result  net.IP
+	}
+	cases := []testCase{
+		testCase{"192.168.0.0/24", net.ParseIP("192.168.0.10"), 5, net.ParseIP("192.168.0.15")},
+		testCase{"192.168.0.0/24", net.ParseIP("192.168.0.250"), 10, net.ParseIP("192.168.0.4")},
+		testCase{"192.168.0.0/24", net.ParseIP("192.168.0.3"), -5, net.ParseIP("192.168.0.254")},
+		testCase{"192.168.0.0/24", net.IP{192, 168, 0, 3}, 256 * 3, net.ParseIP("192.168.0.3")},
+		testCase{"192.168.0.0/30", net.ParseIP("192.168.0.1"), -1 << 62, net.ParseIP("192.168.0.1")},
+		testCase{"10.0.0.5/32", net.ParseIP("10.0.0.5"), 12345, net.ParseIP("10.0.0.5")},
+		testCase{"2001:db8::/126", net.ParseIP("2001:db8::3"), 1, net.ParseIP("2001:db8::")},
+		testCase{"::/0", net.ParseIP("ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff"), 2, net.ParseIP("::1")},
+	}
+	for _, test := range cases {
+		n := mustParseCIDR(test.network)
+		result, err := AddWithinNetwork(n, test.ip, test.offset)
+		if err != nil {
+			t.Errorf("unexpected error %v when adding %v to %v in %v", err, test.offset, test.ip, n)
+			continue
+		}
+		if !test.result.Equal(result) || len(result) != len(n.IP) {
+			t.Errorf("expecting %v, got %v when adding %v to %v in %v", test.result, result, test.offset, test.ip, n)
+		}
+	}
+}
+
+func TestAddWithinNetworkFaults(t *testing.T) {
+	type faultCase struct {
+		network *net.IPNet
+		ip      net.IP
+	}
+	faultCases := []faultCase{
+		faultCase{mustParseCIDR("192.168.0.0/24"), net.ParseIP("192.168.1.1")},
+		faultCase{mustParseCIDR("192.168.0.0/24"), net.ParseIP("2001:db8::1")},
+		faultCase{&net.IPNet{IP: net.IP{10, 0, 0, 0}, Mask: net.IPMask{255, 0, 255, 0}}, net.ParseIP("10.0.0.1")},
+		faultCase{nil, net.ParseIP("10.0.0.1")},
+	}
+	for _, test := range faultCases {
+		if result, err := AddWithinNetwork(test.network, test.ip, 1); err == nil {
+			t.Errorf("didn't get an error when adding to %v in %v, got %v", test.ip, test.network, result)
+		}
+	}
+}
+
+func TestExpandCIDR(t *testing.T) {
+	type testCase struct {
+		network string
+		max     int
+		result  []string
+	}
+	cases := []testCase{
+		testCase{"192.168.0.0/30", 4, []string{"192.168.0.0", "192.168.0.1", "192.168.0.2", "192.168.0.3"}},
+		testCase{"192.168.0.1/32", 1, []string{"192.168.0.1"}},
+		testCase{"255.255.255.254/31", 10, []string{"255.255.255.254", "255.255.255.255"}},
+		testCase{"2001:db8::/127", 2, []string{"2001:db8::", "2001:db8::1"}},
+	}
+	for _, test := range cases {
+		result, err := ExpandCIDR(mustParseCIDR(test.network), test.max)
+		if err != nil {
+			t.Errorf("unexpected error %v when expanding %v", err, test.network)
+			continue
+		}
+		if fmt.Sprint(test.result) != fmt.Sprint(result) {
+			t.Errorf("expecting %v, got %v when expanding %v", test.result, result, test.network)
+		}
+	}
+}
+
+func TestExpandCIDRFaults(t *testing.T) {
+	type faultCase struct {
+		network *net.IPNet
+		max     int
+	}
+	faultCases := []faultCase{
+		faultCase{mustParseCIDR("192.168.0.0/30"), 3},
+		faultCase{mustParseCIDR("10.0.0.0/8"), 65536},
+		faultCase{mustParseCIDR("2001:db8::/64"), 1 << 30},
+		faultCase{mustParseCIDR("::/0"), 1 << 30},
+		faultCase{mustParseCIDR("192.168.0.0/32"), -1},
+		faultCase{&net.IPNet{IP: net.IP{10, 0, 0, 0}, Mask: net.IPMask{255, 0, 255, 0}}, 1 << 30},
+	}
+	for _, test := range faultCases {
+		if result, err := ExpandCIDR(test.network, test.max); err == nil {
+			t.Errorf("didn't get an error when expanding %v with limit %v, got %v", test.network, test.max, result)
+		}
+	}
+}
+
 func TestCompareIPs(t *testing.T) {
 	type testCase struct {
 		a      net.IP
@@ -130,6 +265,34 @@ func TestCompareIPsFaults(t *testing.T) {
 	}
 }
 
+func TestCompare(t *testing.T) {
+	type testCase struct {
+		a      net.IP
+		b      net.IP
+		result int
+	}
+	cases := []testCase{
+		testCase{net.ParseIP("192.168.0.0"), net.ParseIP("192.168.0.1"), -1},
+		testCase{net.ParseIP("192.168.0.0"), net.ParseIP("192.168.0.0"), 0},
+		testCase{net.ParseIP("192.168.0.1"), net.ParseIP("192.168.0.0"), 1},
+		testCase{[]byte{192, 168, 0, 1}, net.ParseIP("192.168.0.1"), 0},
+		testCase{[]byte{192, 168, 0, 1}, net.ParseIP("192.168.0.2"), -1},
+		testCase{net.ParseIP("192.168.0.1"), []byte{10, 0, 0, 1}, 1},
+		testCase{net.ParseIP("::1"), []byte{0, 0, 0, 0}, -1},
+		testCase{[]byte{255, 255, 255, 255}, net.ParseIP("2001:db8::"), -1},
+		testCase{net.ParseIP("2001:db8::1"), net.ParseIP("2001:db8::"), 1},
+		testCase{nil, net.ParseIP("::"), -1},
+		testCase{net.ParseIP("::"), []byte{1, 2, 3}, 1},
+		testCase{nil, nil, 0},
+	}
+	for _, test := range cases {
+		result := Compare(test.a, test.b)
+		if test.result != result {
+			t.Errorf("expecting %v, got %v when comparing ip addresses %v and %v", test.result, result, test.a, test.b)
+		}
+	}
+}
+
 func TestIPRangeIterator(t *testing.T) {
 	type testCase struct {
 		first    net.IP
@@ -141,6 +304,10 @@ func TestIPRangeIterator(t *testing.T) {
 			[]net.IP{net.ParseIP("192.168.0.0"), net.ParseIP("192.168.0.1")}},
 		testCase{net.ParseIP("192.168.0.0"), net.ParseIP("192.168.0.0"), []net.IP{net.ParseIP("192.168.0.0")}},
 		testCase{net.ParseIP("192.168.0.20"), net.ParseIP("192.168.0.10"), []net.IP{}},
+		testCase{net.ParseIP("255.255.255.254"), net.ParseIP("255.255.255.255"),
+			[]net.IP{net.ParseIP("255.255.255.254"), net.ParseIP("255.255.255.255")}},
+		testCase{net.ParseIP("ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff"), net.ParseIP("ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff"),
+			[]net.IP{net.ParseIP("ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff")}},
 	}
 NEXT_CASE:
 	for _, test := range cases {
@@ -205,6 +372,120 @@ NEXT_CASE:
 	}
 }
 
+func TestIPRangeIteratorResume(t *testing.T) {
+	type testCase struct {
+		first net.IP
+		last  net.IP
+		skip  int
+	}
+	cases := []testCase{
+		testCase{net.ParseIP("192.168.0.250"), net.ParseIP("192.168.1.5"), 3},
+		testCase{[]byte{10, 0, 0, 0}, []byte{10, 0, 0, 3}, 0},
+		testCase{[]byte{10, 0, 0, 0}, []byte{10, 0, 0, 3}, 4},
+		testCase{net.ParseIP("::100"), net.ParseIP("::10f"), 7},
+		testCase{[]byte{255, 255, 255, 254}, []byte{255, 255, 255, 255}, 2},
+	}
+	for _, test := range cases {
+		iter := GetIPRangeIterator(test.first, test.last)
+		for i := 0; i < test.skip; i++ {
+			iter.Next()
+		}
+		state, err := iter.(encoding.BinaryMarshaler).MarshalBinary()
+		if err != nil {
+			t.Errorf("failed to marshal %v: %v", iter, err)
+			continue
+		}
+		resumed, err := ResumeIPRangeIterator(state)
+		if err != nil {
+			t.Errorf("failed to resume %v: %v", iter, err)
+			continue
+		}
+		if fmt.Sprintf("%v", iter) != fmt.Sprintf("%v", resumed) {
+			t.Errorf("expecting %v, got %v after resuming", iter, resumed)
+		}
+		for {
+			expected, expectedOk := iter.Next()
+			value, ok := resumed.Next()
+			if expectedOk != ok || !expected.Equal(value) {
+				t.Errorf("resumed iterator produced (%v, %v), expecting (%v, %v)", value, ok, expected, expectedOk)
+				break
+			}
+			if !ok {
+				break
+			}
+		}
+	}
+}
+
+func TestResumeIPRangeIteratorFaults(t *testing.T) {
+	faultCases := [][]byte{
+		nil,
+		[]byte{},
+		[]byte{2, 4, 0, 10, 0, 0, 0, 10, 0, 0, 1, 10, 0, 0, 0},
+		[]byte{1, 5, 0, 10, 0, 0, 0, 0, 10, 0, 0, 1, 0, 10, 0, 0, 0, 0},
+		[]byte{1, 4, 2, 10, 0, 0, 0, 10, 0, 0, 1, 10, 0, 0, 0},
+		[]byte{1, 4, 0, 10, 0, 0, 0, 10, 0, 0, 1},
+		[]byte{1, 4, 10, 0, 0, 0, 10, 0, 0, 1, 10, 0, 0, 0},
+	}
+	for _, state := range faultCases {
+		if _, err := ResumeIPRangeIterator(state); !errors.Is(err, ErrInvalidEncoding) {
+			t.Errorf("expecting an invalid encoding error when resuming from %v, got %v", state, err)
+		}
+	}
+}
+
+func TestNewIPRangeIterator(t *testing.T) {
+	type testCase struct {
+		first net.IP
+		last  net.IP
+		count int
+	}
+	cases := []testCase{
+		testCase{net.ParseIP("::fffe:ffff:fffe"), net.ParseIP("::ffff:0.0.0.1"), 4},
+		testCase{net.ParseIP("::ffff:255.255.255.254"), net.ParseIP("::ffff:255.255.255.255"), 2},
+		testCase{net.ParseIP("::1:0:0:0"), net.ParseIP("::1:0:0:1"), 2},
+		testCase{[]byte{255, 255, 255, 254}, []byte{255, 255, 255, 255}, 2},
+		testCase{[]byte{10, 0, 0, 2}, []byte{10, 0, 0, 1}, 0},
+	}
+	for _, test := range cases {
+		iter, err := NewIPRangeIterator(test.first, test.last)
+		if err != nil {
+			t.Errorf("unexpected error %v for %v-%v", err, test.first, test.last)
+			continue
+		}
+		count := 0
+		for _, ok := iter.Next(); ok; _, ok = iter.Next() {
+			count++
+		}
+		if count != test.count {
+			t.Errorf("expecting %v addresses, got %v for %v-%v", test.count, count, test.first, test.last)
+		}
+	}
+
+	type faultCase struct {
+		first net.IP
+		last  net.IP
+		kind  error
+	}
+	faultCases := []faultCase{
+		faultCase{net.ParseIP("::ffff:255.255.255.254"), net.ParseIP("::1:0:0:1"), ErrInvalidRange},
+		faultCase{net.ParseIP("::"), net.ParseIP("2001:db8::"), ErrInvalidRange},
+		faultCase{[]byte{10, 0, 0, 1}, net.ParseIP("10.0.0.2"), ErrFamilyMismatch},
+		faultCase{nil, nil, ErrFamilyMismatch},
+	}
+	for _, test := range faultCases {
+		if _, err := NewIPRangeIterator(test.first, test.last); !errors.Is(err, test.kind) {
+			t.Errorf("expecting an error of kind %q, got %v for %v-%v", test.kind, err, test.first, test.last)
+		}
+	}
+
+	// states with such ranges are rejected as well
+	state, _ := GetIPRangeIterator(net.ParseIP("::ffff:255.255.255.254"), net.ParseIP("::1:0:0:1")).(encoding.BinaryMarshaler).MarshalBinary()
+	if _, err := ResumeIPRangeIterator(state); !errors.Is(err, ErrInvalidEncoding) || !errors.Is(err, ErrInvalidRange) {
+		t.Errorf("expecting an invalid encoding error, got %v", err)
+	}
+}
+
 func ExampleNext() {
 	ip := net.ParseIP("192.168.0.1")
 	ok := Next(ip)
@@ -249,3 +530,26 @@ func ExampleIPRangeIterator_Next() {
 	// 192.168.0.4
 	// 192.168.0.5
 }
+
+func ExampleCompare() {
+	ips := []net.IP{
+		net.ParseIP("2001:db8::1"),
+		net.ParseIP("192.168.0.2"),
+		[]byte{192, 168, 0, 1},
+		net.ParseIP("::1"),
+	}
+	sort.Slice(ips, func(i, j int) bool { return Compare(ips[i], ips[j]) < 0 })
+	fmt.Println(ips)
+
+	// Output:
+	// [::1 192.168.0.1 192.168.0.2 2001:db8::1]
+}
+
+// mustParseCIDR parses a network in CIDR notation and panics on failure.
+func mustParseCIDR(s string) *net.IPNet {
+	_, n, err := net.ParseCIDR(s)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
@@ -0,0 +1,38 @@
+// SPDX-License-Identifier: MIT-0
+
+package iputils
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net"
+)
+
+// SortableKey returns a string which sorts lexically in the same order as
+// addresses sort with Compare.  The key is 32 lowercase hex digits of the
+// 16-byte form of the address, so IPv4 addresses are encoded as their
+// IPv4-mapped IPv6 equivalents.  If ip is not valid, an empty string is
+// returned.
+func SortableKey(ip net.IP) string {
+	ip16 := ip.To16()
+	if ip16 == nil {
+		return ""
+	}
+	return hex.EncodeToString(ip16)
+}
+
+// ParseSortableKey returns the address encoded with SortableKey.  IPv4
+// addresses are returned in the 4-byte form.
+func ParseSortableKey(key string) (net.IP, error) {
+	if len(key) != 2*IPv6Size {
+		return nil, fmt.Errorf("%w: sortable key %q", ErrInvalidEncoding, key)
+	}
+	ip, err := hex.DecodeString(key)
+	if err != nil || hex.EncodeToString(ip) != key {
+		return nil, fmt.Errorf("%w: sortable key %q", ErrInvalidEncoding, key)
+	}
+	if ip4 := net.IP(ip).To4(); ip4 != nil {
+		return ip4, nil
+	}
+	return ip, nil
+}
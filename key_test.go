@@ -0,0 +1,76 @@
+// SPDX-License-Identifier: MIT-0
+
+package iputils
+
+import (
+	"net"
+	"sort"
+	"testing"
+)
+
+func TestSortableKey(t *testing.T) {
+	type testCase struct {
+		ip  net.IP
+		key string
+	}
+	cases := []testCase{
+		testCase{net.ParseIP("::"), "00000000000000000000000000000000"},
+		testCase{net.ParseIP("::1"), "00000000000000000000000000000001"},
+		testCase{net.IP{192, 0, 2, 1}, "00000000000000000000ffffc0000201"},
+		testCase{net.ParseIP("192.0.2.1"), "00000000000000000000ffffc0000201"},
+		testCase{net.ParseIP("2001:db8::ab"), "20010db80000000000000000000000ab"},
+		testCase{nil, ""},
+	}
+	for _, test := range cases {
+		key := SortableKey(test.ip)
+		if test.key != key {
+			t.Errorf("expecting %q, got %q for %v", test.key, key, test.ip)
+			continue
+		}
+		if test.ip == nil {
+			continue
+		}
+		ip, err := ParseSortableKey(key)
+		if err != nil || !test.ip.Equal(ip) {
+			t.Errorf("expecting %v, got (%v, %v) when parsing %q", test.ip, ip, err, key)
+		}
+	}
+}
+
+func TestSortableKeyOrder(t *testing.T) {
+	ips := []net.IP{
+		net.ParseIP("2001:db8::10"),
+		net.ParseIP("10.0.0.2"),
+		net.ParseIP("ffff::"),
+		net.ParseIP("::1"),
+		net.IP{9, 255, 255, 255},
+		net.ParseIP("2001:db8::9"),
+	}
+	keys := make([]string, len(ips))
+	for i, ip := range ips {
+		keys[i] = SortableKey(ip)
+	}
+	sort.Strings(keys)
+	sort.Slice(ips, func(i, j int) bool { return Compare(ips[i], ips[j]) < 0 })
+	for i := range ips {
+		if ip, _ := ParseSortableKey(keys[i]); !ips[i].Equal(ip) {
+			t.Errorf("key order %v doesn't match address order %v", keys, ips)
+			break
+		}
+	}
+}
+
+func TestParseSortableKeyFaults(t *testing.T) {
+	faultCases := []string{
+		"",
+		"0000000000000000000000000000001",
+		"000000000000000000000000000000001",
+		"0000000000000000000000000000000g",
+		"20010DB80000000000000000000000AB",
+	}
+	for _, test := range faultCases {
+		if ip, err := ParseSortableKey(test); err == nil {
+			t.Errorf("didn't get an error when parsing %q, got %v", test, ip)
+		}
+	}
+}
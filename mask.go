@@ -0,0 +1,94 @@
+// SPDX-License-Identifier: MIT-0
+
+package iputils
+
+import (
+	"fmt"
+	"net"
+)
+
+// IsContiguousMask reports whether the mask is an IPv4 or IPv6 mask
+// consisting of leading ones followed by zeros only.
+func IsContiguousMask(m net.IPMask) bool {
+	_, err := MaskToPrefix(m)
+	return err == nil
+}
+
+// MaskToPrefix returns the prefix length of the mask.  Unlike
+// net.IPMask.Size, which returns 0, 0 for masks it doesn't understand, it
+// returns a descriptive error for non-contiguous masks and masks of
+// invalid size.
+func MaskToPrefix(m net.IPMask) (int, error) {
+	if len(m) != IPv4Size && len(m) != IPv6Size {
+		return 0, fmt.Errorf("%w: %v has size %v", ErrInvalidMask, m, len(m))
+	}
+	ones, bits := m.Size()
+	if bits == 0 {
+		return 0, fmt.Errorf("%w: %v is not contiguous", ErrInvalidMask, maskString(m))
+	}
+	return ones, nil
+}
+
+// MaskMatcher matches ip addresses against an address and a possibly
+// non-contiguous netmask.  An address matches when it is equal to IP in
+// every bit set in Mask.  Wildcard masks of Cisco-style ACLs, where set bits
+// are the ones which don't matter, must be inverted first.
+type MaskMatcher struct {
+	IP   net.IP
+	Mask net.IPMask
+}
+
+// NewMaskMatcher returns a matcher for the address and the mask.
+func NewMaskMatcher(ip net.IP, mask net.IPMask) (*MaskMatcher, error) {
+	switch len(mask) {
+	case IPv4Size:
+		ip = ip.To4()
+	case IPv6Size:
+		ip = ip.To16()
+	default:
+		return nil, fmt.Errorf("%w: %v has size %v", ErrInvalidMask, mask, len(mask))
+	}
+	if ip == nil {
+		return nil, fmt.Errorf("%w: address and mask %v have different families", ErrFamilyMismatch, maskString(mask))
+	}
+	return &MaskMatcher{IP: ip.Mask(mask), Mask: append(net.IPMask(nil), mask...)}, nil
+}
+
+// Match reports whether ip matches the address and the mask.
+func (m *MaskMatcher) Match(ip net.IP) bool {
+	if len(m.Mask) == IPv4Size {
+		ip = ip.To4()
+	} else {
+		ip = ip.To16()
+	}
+	if ip == nil {
+		return false
+	}
+	for i := range ip {
+		if ip[i]&m.Mask[i] != m.IP[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Network returns the equivalent network if the mask is contiguous.
+func (m *MaskMatcher) Network() (*net.IPNet, bool) {
+	if !IsContiguousMask(m.Mask) {
+		return nil, false
+	}
+	return &net.IPNet{IP: CopyIP(m.IP), Mask: append(net.IPMask(nil), m.Mask...)}, true
+}
+
+func (m *MaskMatcher) String() string {
+	return fmt.Sprintf("%v/%v", m.IP, maskString(m.Mask))
+}
+
+// maskString formats IPv4 masks in the dotted notation and IPv6 masks as
+// IPv6 addresses.
+func maskString(m net.IPMask) string {
+	if len(m) == IPv4Size || len(m) == IPv6Size {
+		return net.IP(m).String()
+	}
+	return m.String()
+}
@@ -0,0 +1,104 @@
+// SPDX-License-Identifier: MIT-0
+
+package iputils
+
+import (
+	"fmt"
+	"net"
+	"testing"
+)
+
+func TestMaskToPrefix(t *testing.T) {
+	type testCase struct {
+		mask   net.IPMask
+		prefix int
+	}
+	cases := []testCase{
+		testCase{net.CIDRMask(24, 32), 24},
+		testCase{net.CIDRMask(0, 32), 0},
+		testCase{net.CIDRMask(32, 32), 32},
+		testCase{net.CIDRMask(64, 128), 64},
+		testCase{net.IPv4Mask(255, 255, 254, 0), 23},
+	}
+	for _, test := range cases {
+		if !IsContiguousMask(test.mask) {
+			t.Errorf("mask %v is reported as non-contiguous", test.mask)
+		}
+		prefix, err := MaskToPrefix(test.mask)
+		if err != nil {
+			t.Errorf("unexpected error %v for mask %v", err, test.mask)
+			continue
+		}
+		if test.prefix != prefix {
+			t.Errorf("expecting %v, got %v for mask %v", test.prefix, prefix, test.mask)
+		}
+	}
+}
+
+func TestMaskToPrefixFaults(t *testing.T) {
+	faultCases := []net.IPMask{
+		nil,
+		net.IPMask{255, 255, 0},
+		net.IPv4Mask(255, 0, 255, 0),
+		net.IPv4Mask(0, 255, 255, 255),
+		net.IPv4Mask(255, 255, 255, 1),
+	}
+	for _, test := range faultCases {
+		if IsContiguousMask(test) {
+			t.Errorf("mask %v is reported as contiguous", test)
+		}
+		if prefix, err := MaskToPrefix(test); err == nil {
+			t.Errorf("didn't get an error for mask %v, got %v", test, prefix)
+		}
+	}
+}
+
+func TestMaskMatcher(t *testing.T) {
+	type testCase struct {
+		ip     net.IP
+		result bool
+	}
+	m, err := NewMaskMatcher(net.ParseIP("10.1.2.3"), net.IPv4Mask(255, 0, 255, 0))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	cases := []testCase{
+		testCase{net.ParseIP("10.0.2.0"), true},
+		testCase{net.IP{10, 255, 2, 255}, true},
+		testCase{net.ParseIP("10.1.3.3"), false},
+		testCase{net.ParseIP("11.1.2.3"), false},
+		testCase{net.ParseIP("2001:db8::1"), false},
+	}
+	for _, test := range cases {
+		if result := m.Match(test.ip); test.result != result {
+			t.Errorf("expecting %v, got %v when matching %v against %v", test.result, result, test.ip, m)
+		}
+	}
+	if n, ok := m.Network(); ok {
+		t.Errorf("non-contiguous matcher %v converted to network %v", m, n)
+	}
+
+	m, err = NewMaskMatcher(net.ParseIP("10.1.2.3"), net.CIDRMask(16, 32))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if n, ok := m.Network(); !ok || n.String() != "10.1.0.0/16" {
+		t.Errorf("expecting 10.1.0.0/16, got (%v, %v)", n, ok)
+	}
+
+	if _, err := NewMaskMatcher(net.ParseIP("2001:db8::1"), net.CIDRMask(16, 32)); err == nil {
+		t.Errorf("didn't get an error for IPv6 address with IPv4 mask")
+	}
+	if _, err := NewMaskMatcher(net.ParseIP("10.0.0.1"), net.IPMask{255}); err == nil {
+		t.Errorf("didn't get an error for invalid mask")
+	}
+}
+
+func ExampleMaskToPrefix() {
+	fmt.Println(MaskToPrefix(net.IPv4Mask(255, 255, 255, 0)))
+	fmt.Println(MaskToPrefix(net.IPv4Mask(255, 0, 255, 0)))
+
+	// Output:
+	// 24 <nil>
+	// 0 invalid mask: 255.0.255.0 is not contiguous
+}
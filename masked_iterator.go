@@ -0,0 +1,110 @@
+// SPDX-License-Identifier: MIT-0
+
+package iputils
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+)
+
+// GetMaskedIterator returns an iterator over the addresses from first to
+// last (inclusive) whose bits selected by mask are equal to the bits of
+// value, e.g. value 0.0.0.1 with mask 0.0.0.255 selects addresses ending in
+// .1, and value 0.0.0.0 with mask 0.0.0.1 selects even addresses.  The mask
+// doesn't need to be contiguous.
+//
+// Non-matching addresses are skipped arithmetically, so the cost of every
+// step doesn't depend on how many addresses are skipped.
+//
+// If both addresses are IPv4, the mask and the value must be IPv4 too,
+// otherwise everything is taken in the 16-byte form and the mask must be
+// 16 bytes long.  If the arguments don't fit, the iterator doesn't produce
+// any addresses.
+func GetMaskedIterator(first, last, value net.IP, mask net.IPMask) IPRangeIterator {
+	f, l, v := first.To4(), last.To4(), value.To4()
+	if f == nil || l == nil {
+		f, l, v = first.To16(), last.To16(), value.To16()
+	}
+	iter := &maskedIterator{first: f, last: l, pattern: v, mask: mask}
+	if f == nil || l == nil || v == nil || len(mask) != len(f) {
+		return iter
+	}
+	iter.width = len(f) * 8
+	iter.maskValue = ipToInt(net.IP(mask))
+	iter.value = ipToInt(v.Mask(mask))
+	iter.lastValue = ipToInt(l)
+	iter.next = iter.firstMatch(ipToInt(f))
+	if iter.next != nil && iter.next.Cmp(iter.lastValue) > 0 {
+		iter.next = nil
+	}
+	return iter
+}
+
+type maskedIterator struct {
+	first   net.IP
+	last    net.IP
+	pattern net.IP
+	mask    net.IPMask
+
+	width     int
+	maskValue *big.Int
+	value     *big.Int
+	lastValue *big.Int
+
+	// next is nil when the iteration is over
+	next *big.Int
+}
+
+func (iter *maskedIterator) Next() (ip net.IP, ok bool) {
+	if iter.next == nil {
+		return nil, false
+	}
+	ip = intToIP(iter.next, iter.width/8)
+	iter.next = iter.nextMatch(iter.next)
+	if iter.next != nil && iter.next.Cmp(iter.lastValue) > 0 {
+		iter.next = nil
+	}
+	return ip, true
+}
+
+func (iter *maskedIterator) String() string {
+	return fmt.Sprintf("MaskedIterator(%v -> %v, %v/%v)", iter.first, iter.last, iter.pattern, maskString(iter.mask))
+}
+
+// firstMatch returns the smallest matching value not less than x or nil
+func (iter *maskedIterator) firstMatch(x *big.Int) *big.Int {
+	candidate := new(big.Int).AndNot(x, iter.maskValue)
+	candidate.Or(candidate, iter.value)
+	cmp := candidate.Cmp(x)
+	if cmp == 0 {
+		return candidate
+	}
+	// the highest differing bit is a masked one, as the free bits are
+	// taken from x
+	bit := new(big.Int).Xor(candidate, x).BitLen() - 1
+	if cmp > 0 {
+		// clear the free bits below it
+		below := new(big.Int).Lsh(big.NewInt(1), uint(bit))
+		below.Sub(below, big.NewInt(1))
+		return candidate.AndNot(candidate, below.AndNot(below, iter.maskValue))
+	}
+	// the free bits above it have to be incremented: set the free bits up
+	// to it and take the next match
+	upTo := new(big.Int).Lsh(big.NewInt(1), uint(bit+1))
+	upTo.Sub(upTo, big.NewInt(1))
+	return iter.nextMatch(candidate.Or(candidate, upTo))
+}
+
+// nextMatch returns the smallest matching value bigger than x, with the
+// masked bits of x ignored, or nil
+func (iter *maskedIterator) nextMatch(x *big.Int) *big.Int {
+	result := new(big.Int).Or(x, iter.maskValue)
+	result.Add(result, big.NewInt(1))
+	result.AndNot(result, iter.maskValue)
+	result.Or(result, iter.value)
+	if result.BitLen() > iter.width {
+		return nil
+	}
+	return result
+}
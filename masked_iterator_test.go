@@ -0,0 +1,86 @@
+// SPDX-License-Identifier: MIT-0
+
+package iputils
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+	"testing"
+)
+
+// collectIPs returns all addresses produced by the iterator as strings
+func collectIPs(iter IPRangeIterator) []string {
+	result := []string{}
+	for ip, ok := iter.Next(); ok; ip, ok = iter.Next() {
+		result = append(result, ip.String())
+	}
+	return result
+}
+
+func TestMaskedIterator(t *testing.T) {
+	type testCase struct {
+		first    string
+		last     string
+		value    string
+		mask     net.IPMask
+		sequence string
+		limit    int
+	}
+	cases := []testCase{
+		testCase{"10.0.0.0", "10.0.2.255", "0.0.0.1", net.IPMask{0, 0, 0, 255}, "[10.0.0.1 10.0.1.1 10.0.2.1]", 0},
+		testCase{"10.0.0.2", "10.0.2.0", "0.0.0.1", net.IPMask{0, 0, 0, 255}, "[10.0.1.1]", 0},
+		testCase{"10.0.0.3", "10.0.0.9", "0.0.0.0", net.IPMask{0, 0, 0, 1}, "[10.0.0.4 10.0.0.6 10.0.0.8]", 0},
+		testCase{"10.0.0.0", "10.255.255.255", "0.7.0.1", net.IPMask{0, 255, 255, 255}, "[10.7.0.1]", 0},
+		testCase{"10.0.0.0", "10.0.0.255", "0.0.0.200", net.IPMask{0, 0, 0, 0xf0}, "[10.0.0.192 10.0.0.193 10.0.0.194 10.0.0.195 10.0.0.196 10.0.0.197 10.0.0.198 10.0.0.199 10.0.0.200 10.0.0.201 10.0.0.202 10.0.0.203 10.0.0.204 10.0.0.205 10.0.0.206 10.0.0.207]", 0},
+		testCase{"255.255.255.250", "255.255.255.255", "0.0.0.1", net.IPMask{0, 0, 0, 1}, "[255.255.255.251 255.255.255.253 255.255.255.255]", 0},
+		testCase{"255.255.255.0", "255.255.255.255", "0.0.0.0", net.IPMask{0, 0, 1, 0}, "[]", 0},
+		testCase{"10.0.0.5", "10.0.0.1", "0.0.0.0", net.IPMask{0, 0, 0, 0}, "[]", 0},
+		testCase{"10.0.0.1", "10.0.0.1", "0.0.0.0", net.IPMask{0, 0, 0, 0}, "[10.0.0.1]", 0},
+		testCase{"2001:db8::", "2001:db8:3::", "::1", net.CIDRMask(128, 128), "[]", 0},
+		testCase{"2001:db8::", "2001:db8:3::1", "0:0:0:0:1::1", net.IPMask{0, 0, 0, 0, 0, 0, 0, 0, 0, 0xff, 0, 0, 0, 0, 0, 0xff}, "[2001:db8::1:0:0:1 2001:db8::1:0:0:101]", 2},
+		testCase{"2001:db8::", "2001:db8::2:0:0:1", "::1", net.IPMask{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0xff}, "[2001:db8::1 2001:db8::101 2001:db8::201 2001:db8::301]", 4},
+		testCase{"10.0.0.0", "10.0.0.255", "0.0.0.1", net.CIDRMask(128, 128), "[]", 0},
+		testCase{"2001:db8::", "2001:db8::ff", "::1", net.IPMask{0, 0, 0, 255}, "[]", 0},
+	}
+	for _, test := range cases {
+		iter := GetMaskedIterator(net.ParseIP(test.first), net.ParseIP(test.last), net.ParseIP(test.value), test.mask)
+		if test.limit > 0 {
+			// only the beginning of a long sequence
+			var sequence []string
+			for i := 0; i < test.limit; i++ {
+				ip, _ := iter.Next()
+				sequence = append(sequence, ip.String())
+			}
+			if fmt.Sprint(sequence) != test.sequence {
+				t.Errorf("expecting %v, got %v from %v", test.sequence, sequence, iter)
+			}
+			continue
+		}
+		if sequence := fmt.Sprint(collectIPs(iter)); sequence != test.sequence {
+			t.Errorf("expecting %v, got %v from %v", test.sequence, sequence, iter)
+		}
+	}
+}
+
+func TestMaskedIteratorMatchesFilter(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		first := net.IP{10, 0, byte(rnd.Intn(4)), byte(rnd.Intn(256))}
+		last := net.IP{10, 0, byte(rnd.Intn(4)), byte(rnd.Intn(256))}
+		value := net.IP{0, 0, byte(rnd.Intn(256)), byte(rnd.Intn(256))}
+		mask := net.IPMask{0, 0, byte(rnd.Intn(256)), byte(rnd.Intn(256))}
+
+		expected := []string{}
+		iter := GetIPRangeIterator(first, last)
+		for ip, ok := iter.Next(); ok; ip, ok = iter.Next() {
+			if ip.Mask(mask).Equal(value.Mask(mask)) {
+				expected = append(expected, ip.String())
+			}
+		}
+		masked := GetMaskedIterator(first, last, value, mask)
+		if result := collectIPs(masked); fmt.Sprint(expected) != fmt.Sprint(result) {
+			t.Errorf("expecting %v, got %v from %v", expected, result, masked)
+		}
+	}
+}
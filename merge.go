@@ -0,0 +1,192 @@
+// SPDX-License-Identifier: MIT-0
+
+package iputils
+
+import (
+	"container/heap"
+)
+
+// RangeSource is a stream of ip ranges
+type RangeSource interface {
+
+	// Next returns the next range and true if the next range exists.
+	// If it doesn't exist, an empty range and false are returned.
+	Next() (r IPRange, ok bool)
+}
+
+// GetSliceRangeSource returns a RangeSource producing the given ranges
+func GetSliceRangeSource(ranges []IPRange) RangeSource {
+	return &sliceRangeSource{ranges}
+}
+
+type sliceRangeSource struct {
+	ranges []IPRange
+}
+
+func (s *sliceRangeSource) Next() (r IPRange, ok bool) {
+	if len(s.ranges) == 0 {
+		return IPRange{}, false
+	}
+	r = s.ranges[0]
+	s.ranges = s.ranges[1:]
+	return r, true
+}
+
+// MergeSorted merges several streams of ranges into one normalized stream:
+// ranges are produced in ascending order, and overlapping or adjacent ranges
+// are joined together.  Only one range of every source is kept in memory.
+//
+// Every source must produce ranges sorted by their first address (in the
+// order defined by Compare).  Ranges with the first address bigger than
+// the last one are skipped.  Ranges containing both IPv4 and IPv6
+// addresses are split by family the same way IPSet does it, so IPv4 and
+// IPv6 ranges are never joined with each other.  IPv4 ranges are produced
+// in the 4-byte form.
+func MergeSorted(sources ...RangeSource) RangeSource {
+	m := &mergedRangeSource{}
+	for _, source := range sources {
+		m.push(&familySplitSource{source: source})
+	}
+	return m
+}
+
+// familySplitSource produces the normalized ranges of the source split by
+// family, keeping them sorted.
+type familySplitSource struct {
+	source RangeSource
+
+	// ready are the parts to produce next
+	ready []IPRange
+
+	// pending are the sorted parts which start at the IPv4-mapped block or
+	// after it; they wait until the source passes their first address, as
+	// the following ranges of the source may produce smaller parts
+	pending []IPRange
+}
+
+func (s *familySplitSource) Next() (r IPRange, ok bool) {
+	for len(s.ready) == 0 {
+		r, ok = s.source.Next()
+		if !ok {
+			if len(s.pending) == 0 {
+				return IPRange{}, false
+			}
+			s.ready, s.pending = s.pending, nil
+			break
+		}
+		if r = normalizeRange(r); r.First == nil {
+			continue
+		}
+		// parts of the following ranges don't start before r
+		i := 0
+		for ; i < len(s.pending) && Compare(s.pending[i].First, r.First) <= 0; i++ {
+		}
+		s.ready, s.pending = append(s.ready, s.pending[:i]...), s.pending[i:]
+
+		parts := splitRangeByFamily(r)
+		s.ready = append(s.ready, parts[0])
+		for _, part := range parts[1:] {
+			s.pending = addPending(s.pending, part)
+		}
+	}
+	r, s.ready = s.ready[0], s.ready[1:]
+	return r, true
+}
+
+// addPending adds the part to the sorted list joining it with a part
+// starting at the same address
+func addPending(pending []IPRange, part IPRange) []IPRange {
+	for i, p := range pending {
+		switch c := Compare(p.First, part.First); {
+		case c == 0:
+			if Compare(part.Last, p.Last) > 0 {
+				pending[i].Last = part.Last
+			}
+			return pending
+		case c > 0:
+			pending = append(pending, IPRange{})
+			copy(pending[i+1:], pending[i:])
+			pending[i] = part
+			return pending
+		}
+	}
+	return append(pending, part)
+}
+
+type mergedRangeSource struct {
+	heads rangeHeap
+}
+
+type rangeHead struct {
+	r      IPRange
+	source RangeSource
+}
+
+// push adds the next valid range of the source to the heap
+func (m *mergedRangeSource) push(source RangeSource) {
+	for r, ok := source.Next(); ok; r, ok = source.Next() {
+		if r = normalizeRange(r); r.First != nil {
+			heap.Push(&m.heads, rangeHead{r, source})
+			return
+		}
+	}
+}
+
+func (m *mergedRangeSource) Next() (r IPRange, ok bool) {
+	if len(m.heads) == 0 {
+		return IPRange{}, false
+	}
+	head := heap.Pop(&m.heads).(rangeHead)
+	r = head.r
+	m.push(head.source)
+	for len(m.heads) > 0 && joinable(r, m.heads[0].r) {
+		head = heap.Pop(&m.heads).(rangeHead)
+		if Compare(r.Last, head.r.Last) < 0 {
+			r.Last = head.r.Last
+		}
+		m.push(head.source)
+	}
+	return normalizeRange(r), true
+}
+
+type rangeHeap []rangeHead
+
+func (h rangeHeap) Len() int            { return len(h) }
+func (h rangeHeap) Less(i, j int) bool  { return Compare(h[i].r.First, h[j].r.First) < 0 }
+func (h rangeHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *rangeHeap) Push(x interface{}) { *h = append(*h, x.(rangeHead)) }
+func (h *rangeHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// joinable reports whether b, which doesn't start before a, overlaps with a
+// or immediately follows it.  Both ranges must contain addresses of one
+// family only; ranges of different families are never joinable, even the
+// last IPv6 address before the IPv4-mapped block and 0.0.0.0.
+func joinable(a, b IPRange) bool {
+	if (a.Last.To4() == nil) != (b.First.To4() == nil) {
+		return false
+	}
+	if Compare(b.First, a.Last) <= 0 {
+		return true
+	}
+	next := CopyIP(a.Last.To16())
+	return Next(next) && Compare(next, b.First) == 0
+}
+
+// normalizeRange returns the range with IPv4 addresses in the 4-byte form and
+// IPv6 addresses in the 16-byte form.  If the range is not valid, an empty
+// range is returned.
+func normalizeRange(r IPRange) IPRange {
+	first, last := r.First.To4(), r.Last.To4()
+	if first == nil || last == nil {
+		first, last = r.First.To16(), r.Last.To16()
+	}
+	if first == nil || last == nil || Compare(first, last) > 0 {
+		return IPRange{}
+	}
+	return IPRange{CopyIP(first), CopyIP(last)}
+}
@@ -0,0 +1,156 @@
+// SPDX-License-Identifier: MIT-0
+
+package iputils
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+	"sort"
+	"testing"
+)
+
+// mustParseRanges parses ranges with ParseAny and panics on failure
+func mustParseRanges(specs ...string) []IPRange {
+	result := make([]IPRange, len(specs))
+	for i, s := range specs {
+		e, err := ParseAny(s)
+		if err != nil {
+			panic(err)
+		}
+		result[i] = e.Range
+	}
+	return result
+}
+
+func collectRanges(source RangeSource) []IPRange {
+	var result []IPRange
+	for r, ok := source.Next(); ok; r, ok = source.Next() {
+		result = append(result, r)
+	}
+	return result
+}
+
+func TestMergeSorted(t *testing.T) {
+	type testCase struct {
+		sources [][]IPRange
+		result  string
+	}
+	cases := []testCase{
+		testCase{nil, "[]"},
+		testCase{[][]IPRange{nil, nil}, "[]"},
+		testCase{
+			[][]IPRange{
+				mustParseRanges("10.0.0.0/24", "10.0.2.0/24"),
+				mustParseRanges("10.0.1.0/24", "10.0.3.10"),
+			},
+			"[10.0.0.0-10.0.2.255 10.0.3.10-10.0.3.10]",
+		},
+		testCase{
+			[][]IPRange{
+				mustParseRanges("10.0.0.0-10.0.0.100", "10.0.1.0/24"),
+				mustParseRanges("10.0.0.50-10.0.0.60"),
+				mustParseRanges("10.0.0.90-10.0.0.200", "10.0.1.255-10.0.2.5"),
+			},
+			"[10.0.0.0-10.0.0.200 10.0.1.0-10.0.2.5]",
+		},
+		testCase{
+			[][]IPRange{
+				mustParseRanges("255.255.255.0/24", "::1:0:0:0/128"),
+				mustParseRanges("::/96", "2001:db8::/32"),
+			},
+			"[::-::ffff:ffff 255.255.255.0-255.255.255.255 ::1:0:0:0-::1:0:0:0 2001:db8::-2001:db8:ffff:ffff:ffff:ffff:ffff:ffff]",
+		},
+		testCase{
+			[][]IPRange{
+				mustParseRanges("::fffe:ffff:ff00-::fffe:ffff:ffff"),
+				mustParseRanges("0.0.0.0/24"),
+			},
+			"[::fffe:ffff:ff00-::fffe:ffff:ffff 0.0.0.0-0.0.0.255]",
+		},
+		testCase{
+			[][]IPRange{
+				mustParseRanges("::-::1:0:0:5"),
+				mustParseRanges("10.0.0.0-10.0.0.5"),
+			},
+			"[::-::fffe:ffff:ffff 0.0.0.0-255.255.255.255 ::1:0:0:0-::1:0:0:5]",
+		},
+		testCase{
+			[][]IPRange{
+				mustParseRanges("::-::1:0:0:5", "::fffe:0:0-::1:0:0:9", "10.0.0.0-10.0.0.5", "::1:0:0:7-::1:0:0:20"),
+				mustParseRanges("::fffe:ffff:fff0-::fffe:ffff:ffff", "0.0.0.0/29", "2001:db8::1"),
+			},
+			"[::-::fffe:ffff:ffff 0.0.0.0-255.255.255.255 ::1:0:0:0-::1:0:0:20 2001:db8::1-2001:db8::1]",
+		},
+		testCase{
+			[][]IPRange{
+				[]IPRange{IPRange{}, IPRange{mustParseRanges("10.0.0.5")[0].First, mustParseRanges("10.0.0.1")[0].First}},
+				mustParseRanges("10.0.0.1"),
+			},
+			"[10.0.0.1-10.0.0.1]",
+		},
+	}
+	for _, test := range cases {
+		var sources []RangeSource
+		for _, ranges := range test.sources {
+			sources = append(sources, GetSliceRangeSource(ranges))
+		}
+		result := fmt.Sprint(collectRanges(MergeSorted(sources...)))
+		if test.result != result {
+			t.Errorf("expecting %v, got %v when merging %v", test.result, result, test.sources)
+		}
+		// the result must be the same as the normalization of IPSet
+		var set IPSet
+		for _, ranges := range test.sources {
+			for _, r := range ranges {
+				set.AddRange(r)
+			}
+		}
+		if setResult := fmt.Sprint(set.Ranges()); setResult != result {
+			t.Errorf("IPSet gives %v, MergeSorted gives %v for %v", setResult, result, test.sources)
+		}
+	}
+}
+
+func TestMergeSortedAroundIPv4Block(t *testing.T) {
+	// addresses around the IPv4-mapped block in the 16-byte form
+	points := []string{"::", "::fffe:ffff:fff0", "::fffe:ffff:ffff", "::ffff:0.0.0.0", "::ffff:0.0.0.9",
+		"::ffff:10.0.0.0", "::ffff:255.255.255.255", "::1:0:0:0", "::1:0:0:5", "::1:0:0:9", "2001:db8::"}
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		var sources []RangeSource
+		var set IPSet
+		for j := 0; j < 3; j++ {
+			var ranges []IPRange
+			for k := 0; k < 4; k++ {
+				a, b := rnd.Intn(len(points)), rnd.Intn(len(points))
+				if a > b {
+					a, b = b, a
+				}
+				ranges = append(ranges, IPRange{net.ParseIP(points[a]), net.ParseIP(points[b])})
+			}
+			sort.Slice(ranges, func(a, b int) bool { return Compare(ranges[a].First, ranges[b].First) < 0 })
+			for _, r := range ranges {
+				set.AddRange(r)
+			}
+			sources = append(sources, GetSliceRangeSource(ranges))
+		}
+		if result := fmt.Sprint(collectRanges(MergeSorted(sources...))); result != fmt.Sprint(set.Ranges()) {
+			t.Fatalf("expecting %v, got %v", set.Ranges(), result)
+		}
+	}
+}
+
+func ExampleMergeSorted() {
+	blocklistA := GetSliceRangeSource(mustParseRanges("192.0.2.0/25", "198.51.100.7"))
+	blocklistB := GetSliceRangeSource(mustParseRanges("192.0.2.128/25", "198.51.100.1-198.51.100.10"))
+
+	merged := MergeSorted(blocklistA, blocklistB)
+	for r, ok := merged.Next(); ok; r, ok = merged.Next() {
+		fmt.Println(r)
+	}
+
+	// Output:
+	// 192.0.2.0-192.0.2.255
+	// 198.51.100.1-198.51.100.10
+}
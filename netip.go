@@ -0,0 +1,115 @@
+// SPDX-License-Identifier: MIT-0
+
+package iputils
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+)
+
+// ToPrefix converts a network to netip.Prefix.
+//
+// An IPv4 network is converted to an IPv4 prefix regardless of whether it
+// is stored in the 4-byte or in the IPv4-mapped 16-byte form, the same way
+// the net package interprets it.  Host bits of the address are cleared.
+// An error is returned for nil networks, invalid addresses and
+// non-contiguous masks.
+func ToPrefix(n *net.IPNet) (netip.Prefix, error) {
+	if n == nil {
+		return netip.Prefix{}, fmt.Errorf("%w: network is not specified", ErrInvalidNetwork)
+	}
+	ones, bits := n.Mask.Size()
+	if ones == 0 && bits == 0 {
+		return netip.Prefix{}, fmt.Errorf("%w: network %v has non-contiguous mask", ErrInvalidNetwork, n)
+	}
+	ip4 := n.IP.To4()
+	switch {
+	case ip4 != nil && bits == IPv4Size*8:
+		return netip.PrefixFrom(netip.AddrFrom4([4]byte(ip4)), ones).Masked(), nil
+	case ip4 != nil && ones >= (IPv6Size-IPv4Size)*8:
+		return netip.PrefixFrom(netip.AddrFrom4([4]byte(ip4)), ones-(IPv6Size-IPv4Size)*8).Masked(), nil
+	case bits == IPv6Size*8 && len(n.IP) == IPv6Size:
+		return netip.PrefixFrom(netip.AddrFrom16([16]byte(n.IP)), ones).Masked(), nil
+	}
+	return netip.Prefix{}, fmt.Errorf("%w %v", ErrInvalidNetwork, n)
+}
+
+// FromPrefix converts a netip.Prefix to a network.  IPv4 prefixes produce
+// networks with 4-byte address and mask.  Host bits of the address are
+// cleared.  If the prefix is not valid, nil is returned.
+func FromPrefix(p netip.Prefix) *net.IPNet {
+	if !p.IsValid() {
+		return nil
+	}
+	p = p.Masked()
+	return &net.IPNet{
+		IP:   p.Addr().AsSlice(),
+		Mask: net.CIDRMask(p.Bits(), p.Addr().BitLen()),
+	}
+}
+
+// ToPrefixes converts networks to netip.Prefix values with ToPrefix.
+// The first failed conversion is reported as an error.
+func ToPrefixes(networks []*net.IPNet) ([]netip.Prefix, error) {
+	result := make([]netip.Prefix, len(networks))
+	for i, n := range networks {
+		p, err := ToPrefix(n)
+		if err != nil {
+			return nil, fmt.Errorf("network #%v: %w", i, err)
+		}
+		result[i] = p
+	}
+	return result, nil
+}
+
+// FromPrefixes converts netip.Prefix values to networks with FromPrefix.
+// An error is returned if any of the prefixes is not valid.
+func FromPrefixes(prefixes []netip.Prefix) ([]*net.IPNet, error) {
+	result := make([]*net.IPNet, len(prefixes))
+	for i, p := range prefixes {
+		n := FromPrefix(p)
+		if n == nil {
+			return nil, fmt.Errorf("%w: prefix #%v is not valid", ErrInvalidNetwork, i)
+		}
+		result[i] = n
+	}
+	return result, nil
+}
+
+// RangeOfPrefix returns the first and the last address of the prefix.  If
+// the prefix is not valid, zero addresses are returned.
+func RangeOfPrefix(p netip.Prefix) (first, last netip.Addr) {
+	if !p.IsValid() {
+		return netip.Addr{}, netip.Addr{}
+	}
+	p = p.Masked()
+	bytes := p.Addr().AsSlice()
+	mask := net.CIDRMask(p.Bits(), p.Addr().BitLen())
+	for i := range bytes {
+		bytes[i] |= ^mask[i]
+	}
+	last, _ = netip.AddrFromSlice(bytes)
+	return p.Addr(), last
+}
+
+// UsableRangeOfPrefix returns the first and the last address of the prefix
+// which can be assigned to hosts:
+//
+//   - for IPv4 the network and the broadcast addresses are excluded, except
+//     for /31 point-to-point links (RFC 3021) and /32 host routes;
+//   - for IPv6 the Subnet-Router anycast address (the first one, RFC 4291)
+//     is excluded, except for /127 point-to-point links (RFC 6164) and
+//     /128 host routes.
+//
+// If the prefix is not valid, zero addresses are returned.
+func UsableRangeOfPrefix(p netip.Prefix) (first, last netip.Addr) {
+	first, last = RangeOfPrefix(p)
+	if !p.IsValid() || p.Addr().BitLen()-p.Bits() <= 1 {
+		return first, last
+	}
+	if p.Addr().Is4() {
+		return first.Next(), last.Prev()
+	}
+	return first.Next(), last
+}
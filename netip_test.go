@@ -0,0 +1,131 @@
+// SPDX-License-Identifier: MIT-0
+
+package iputils
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"testing"
+)
+
+func TestToPrefix(t *testing.T) {
+	type testCase struct {
+		network *net.IPNet
+		prefix  netip.Prefix
+	}
+	cases := []testCase{
+		testCase{mustParseCIDR("192.168.0.0/24"), netip.MustParsePrefix("192.168.0.0/24")},
+		testCase{mustParseCIDR("::ffff:192.168.0.0/120"), netip.MustParsePrefix("192.168.0.0/24")},
+		testCase{&net.IPNet{IP: net.ParseIP("192.168.0.77"), Mask: net.CIDRMask(24, 32)},
+			netip.MustParsePrefix("192.168.0.0/24")},
+		testCase{&net.IPNet{IP: net.IP{10, 1, 2, 3}, Mask: net.CIDRMask(104, 128)},
+			netip.MustParsePrefix("10.0.0.0/8")},
+		testCase{mustParseCIDR("0.0.0.0/0"), netip.MustParsePrefix("0.0.0.0/0")},
+		testCase{mustParseCIDR("2001:db8::/32"), netip.MustParsePrefix("2001:db8::/32")},
+		testCase{mustParseCIDR("::/0"), netip.MustParsePrefix("::/0")},
+		testCase{&net.IPNet{IP: net.ParseIP("::ffff:0:0"), Mask: net.CIDRMask(80, 128)},
+			netip.MustParsePrefix("::/80")},
+	}
+	for _, test := range cases {
+		prefix, err := ToPrefix(test.network)
+		if err != nil {
+			t.Errorf("unexpected error %v when converting %v", err, test.network)
+			continue
+		}
+		if test.prefix != prefix {
+			t.Errorf("expecting %v, got %v when converting %v", test.prefix, prefix, test.network)
+		}
+	}
+}
+
+func TestToPrefixFaults(t *testing.T) {
+	faultCases := []*net.IPNet{
+		nil,
+		&net.IPNet{IP: net.ParseIP("10.0.0.0"), Mask: net.IPMask{255, 0, 255, 0}},
+		&net.IPNet{IP: net.ParseIP("2001:db8::"), Mask: net.CIDRMask(8, 32)},
+		&net.IPNet{IP: net.IP{1, 2, 3}, Mask: net.CIDRMask(8, 32)},
+	}
+	for _, test := range faultCases {
+		if _, err := ToPrefix(test); err == nil {
+			t.Errorf("didn't get an error when converting %v", test)
+		}
+	}
+	if _, err := ToPrefixes([]*net.IPNet{mustParseCIDR("10.0.0.0/8"), nil}); err == nil {
+		t.Errorf("didn't get an error when converting a list with nil network")
+	}
+}
+
+func TestFromPrefix(t *testing.T) {
+	type testCase struct {
+		prefix netip.Prefix
+		ip     net.IP
+		mask   net.IPMask
+	}
+	cases := []testCase{
+		testCase{netip.MustParsePrefix("192.168.0.0/24"), net.IP{192, 168, 0, 0}, net.CIDRMask(24, 32)},
+		testCase{netip.MustParsePrefix("192.168.0.1/24"), net.IP{192, 168, 0, 0}, net.CIDRMask(24, 32)},
+		testCase{netip.MustParsePrefix("2001:db8::/32"), net.ParseIP("2001:db8::"), net.CIDRMask(32, 128)},
+	}
+	for _, test := range cases {
+		n := FromPrefix(test.prefix)
+		if n == nil || !test.ip.Equal(n.IP) || len(test.ip) != len(n.IP) || test.mask.String() != n.Mask.String() {
+			t.Errorf("expecting %v/%v, got %v when converting %v", test.ip, test.mask, n, test.prefix)
+		}
+	}
+	if n := FromPrefix(netip.Prefix{}); n != nil {
+		t.Errorf("expecting nil for invalid prefix, got %v", n)
+	}
+	if _, err := FromPrefixes([]netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.Prefix{}}); err == nil {
+		t.Errorf("didn't get an error when converting a list with invalid prefix")
+	}
+}
+
+func ExampleToPrefixes() {
+	networks := []*net.IPNet{mustParseCIDR("10.0.0.0/8"), mustParseCIDR("::ffff:192.168.0.0/112")}
+	prefixes, _ := ToPrefixes(networks)
+	fmt.Println(prefixes)
+
+	back, _ := FromPrefixes(prefixes)
+	fmt.Println(back)
+
+	// Output:
+	// [10.0.0.0/8 192.168.0.0/16]
+	// [10.0.0.0/8 192.168.0.0/16]
+}
+
+func TestRangeOfPrefix(t *testing.T) {
+	type testCase struct {
+		prefix      string
+		first       string
+		last        string
+		usableFirst string
+		usableLast  string
+	}
+	cases := []testCase{
+		testCase{"192.168.0.0/24", "192.168.0.0", "192.168.0.255", "192.168.0.1", "192.168.0.254"},
+		testCase{"192.168.0.77/24", "192.168.0.0", "192.168.0.255", "192.168.0.1", "192.168.0.254"},
+		testCase{"192.168.0.4/30", "192.168.0.4", "192.168.0.7", "192.168.0.5", "192.168.0.6"},
+		testCase{"192.168.0.4/31", "192.168.0.4", "192.168.0.5", "192.168.0.4", "192.168.0.5"},
+		testCase{"192.168.0.4/32", "192.168.0.4", "192.168.0.4", "192.168.0.4", "192.168.0.4"},
+		testCase{"0.0.0.0/0", "0.0.0.0", "255.255.255.255", "0.0.0.1", "255.255.255.254"},
+		testCase{"2001:db8::/64", "2001:db8::", "2001:db8::ffff:ffff:ffff:ffff", "2001:db8::1", "2001:db8::ffff:ffff:ffff:ffff"},
+		testCase{"2001:db8::/126", "2001:db8::", "2001:db8::3", "2001:db8::1", "2001:db8::3"},
+		testCase{"2001:db8::/127", "2001:db8::", "2001:db8::1", "2001:db8::", "2001:db8::1"},
+		testCase{"2001:db8::1/128", "2001:db8::1", "2001:db8::1", "2001:db8::1", "2001:db8::1"},
+	}
+	for _, test := range cases {
+		p := netip.MustParsePrefix(test.prefix)
+		first, last := RangeOfPrefix(p)
+		if first.String() != test.first || last.String() != test.last {
+			t.Errorf("expecting range %v-%v, got %v-%v for %v", test.first, test.last, first, last, p)
+		}
+		first, last = UsableRangeOfPrefix(p)
+		if first.String() != test.usableFirst || last.String() != test.usableLast {
+			t.Errorf("expecting usable range %v-%v, got %v-%v for %v", test.usableFirst, test.usableLast, first, last, p)
+		}
+	}
+	if first, last := UsableRangeOfPrefix(netip.Prefix{}); first.IsValid() || last.IsValid() {
+		t.Errorf("expecting zero addresses for invalid prefix, got %v-%v", first, last)
+	}
+}
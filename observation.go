@@ -0,0 +1,127 @@
+// SPDX-License-Identifier: MIT-0
+
+package iputils
+
+import (
+	"math/big"
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ObservationSet records when addresses were seen and answers questions
+// about a sliding time window ("was this address seen in the last hour").
+//
+// Observations are kept in time buckets of the given resolution, each
+// bucket holding an IPSet, so a burst of addresses from the same network
+// takes little memory.  An address seen in many buckets is stored in each of
+// them, though: memory grows with the number of buckets kept times the
+// ranges in every bucket, so old buckets should be dropped with Expire.
+// Times are only as precise as the resolution: an observation counts as
+// made at any moment of its bucket.
+//
+// The zero value is an empty set with the resolution of one second ready to
+// use.  The methods are safe for concurrent use.
+type ObservationSet struct {
+	mu         sync.Mutex
+	resolution time.Duration
+
+	// buckets are sorted by start
+	buckets []observationBucket
+
+	now func() time.Time
+}
+
+type observationBucket struct {
+	start time.Time
+	set   IPSet
+}
+
+// NewObservationSet returns an empty set with buckets of the given
+// resolution.  A non-positive resolution means one second.
+func NewObservationSet(resolution time.Duration) *ObservationSet {
+	return &ObservationSet{resolution: resolution}
+}
+
+// bucketSize returns the resolution, one second by default
+func (s *ObservationSet) bucketSize() time.Duration {
+	if s.resolution <= 0 {
+		return time.Second
+	}
+	return s.resolution
+}
+
+// currentTime returns the time the windows end at
+func (s *ObservationSet) currentTime() time.Time {
+	if s.now == nil {
+		return time.Now()
+	}
+	return s.now()
+}
+
+// Add records that ip was seen at the given time.  Invalid addresses are
+// ignored.
+func (s *ObservationSet) Add(ip net.IP, timestamp time.Time) {
+	if ip.To16() == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	start := timestamp.Truncate(s.bucketSize())
+	i := sort.Search(len(s.buckets), func(i int) bool { return !s.buckets[i].start.Before(start) })
+	if i == len(s.buckets) || !s.buckets[i].start.Equal(start) {
+		s.buckets = append(s.buckets, observationBucket{})
+		copy(s.buckets[i+1:], s.buckets[i:])
+		s.buckets[i] = observationBucket{start: start}
+	}
+	s.buckets[i].set.AddIP(ip)
+}
+
+// ContainsSince reports whether ip was seen within the window ending now.
+func (s *ObservationSet) ContainsSince(ip net.IP, window time.Duration) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, b := range s.window(window) {
+		if b.set.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Count returns the number of distinct addresses seen within the window
+// ending now.
+func (s *ObservationSet) Count(window time.Duration) *big.Int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var union IPSet
+	for _, b := range s.window(window) {
+		for _, r := range b.set.ranges {
+			union.ranges = insertRange(union.ranges, r)
+		}
+	}
+	return union.Count()
+}
+
+// Expire forgets the observations made before the given age.
+func (s *ObservationSet) Expire(olderThan time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.windowStart(olderThan)
+	s.buckets = append(s.buckets[:0], s.buckets[i:]...)
+}
+
+// window returns the buckets overlapping with the window ending now
+func (s *ObservationSet) window(window time.Duration) []observationBucket {
+	return s.buckets[s.windowStart(window):]
+}
+
+// windowStart returns the index of the first bucket ending after the start
+// of the window
+func (s *ObservationSet) windowStart(window time.Duration) int {
+	since := s.currentTime().Add(-window)
+	return sort.Search(len(s.buckets), func(i int) bool {
+		return s.buckets[i].start.Add(s.bucketSize()).After(since)
+	})
+}
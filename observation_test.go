@@ -0,0 +1,100 @@
+// SPDX-License-Identifier: MIT-0
+
+package iputils
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestObservationSet(t *testing.T) {
+	base := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	now := base
+	s := NewObservationSet(time.Minute)
+	s.now = func() time.Time { return now }
+
+	s.Add(net.ParseIP("10.0.0.1"), base.Add(-50*time.Minute))
+	s.Add(net.ParseIP("10.0.0.2"), base.Add(-20*time.Minute))
+	s.Add(net.ParseIP("::ffff:10.0.0.3"), base.Add(-20*time.Minute+time.Second))
+	s.Add(net.ParseIP("2001:db8::1"), base.Add(-5*time.Minute))
+	s.Add(net.ParseIP("10.0.0.2"), base.Add(-time.Second))
+	s.Add(nil, base)
+
+	type testCase struct {
+		ip       string
+		window   time.Duration
+		contains bool
+	}
+	cases := []testCase{
+		testCase{"10.0.0.1", time.Hour, true},
+		testCase{"10.0.0.1", 30 * time.Minute, false},
+		testCase{"10.0.0.3", 30 * time.Minute, true},
+		testCase{"10.0.0.3", 10 * time.Minute, false},
+		testCase{"10.0.0.2", 10 * time.Second, true},
+		testCase{"2001:db8::1", 5 * time.Minute, true},
+		testCase{"2001:db8::1", time.Minute, false},
+		testCase{"10.0.0.4", time.Hour, false},
+	}
+	for _, test := range cases {
+		if contains := s.ContainsSince(net.ParseIP(test.ip), test.window); contains != test.contains {
+			t.Errorf("expecting %v, got %v for %v within %v", test.contains, contains, test.ip, test.window)
+		}
+	}
+
+	type countCase struct {
+		window time.Duration
+		count  int64
+	}
+	countCases := []countCase{
+		countCase{time.Hour, 4},
+		countCase{30 * time.Minute, 3},
+		countCase{10 * time.Minute, 2},
+		countCase{time.Minute, 1},
+		countCase{-time.Minute, 0},
+	}
+	for _, test := range countCases {
+		if count := s.Count(test.window); count.Int64() != test.count {
+			t.Errorf("expecting %v addresses within %v, got %v", test.count, test.window, count)
+		}
+	}
+
+	s.Expire(30 * time.Minute)
+	if s.ContainsSince(net.ParseIP("10.0.0.1"), time.Hour) {
+		t.Errorf("expired address is still in the set")
+	}
+	if count := s.Count(time.Hour); count.Int64() != 3 {
+		t.Errorf("expecting 3 addresses after expiration, got %v", count)
+	}
+
+	now = base.Add(time.Hour)
+	s.Expire(0)
+	if count := s.Count(24 * time.Hour); count.Sign() != 0 {
+		t.Errorf("expecting an empty set, got %v addresses", count)
+	}
+}
+
+func TestObservationSetZeroValue(t *testing.T) {
+	var s ObservationSet
+	s.Add(net.ParseIP("192.0.2.1"), time.Now())
+	if !s.ContainsSince(net.ParseIP("192.0.2.1"), time.Minute) {
+		t.Errorf("address added to the zero value is not found")
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				s.Add(net.IP{10, 0, byte(i), byte(j)}, time.Now())
+				s.Count(time.Minute)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if count := s.Count(time.Minute); count.Int64() != 401 {
+		t.Errorf("expecting 401 addresses, got %v", count)
+	}
+}
@@ -0,0 +1,170 @@
+// SPDX-License-Identifier: MIT-0
+
+package iputils
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"sort"
+)
+
+// NamedPrefix is a network of an address plan with its name.
+type NamedPrefix struct {
+	Name    string
+	Network *net.IPNet
+}
+
+func (np NamedPrefix) String() string {
+	return fmt.Sprintf("%v (%v)", np.Name, np.Network)
+}
+
+// PlanIssueKind is the kind of a problem found in an address plan.
+type PlanIssueKind int
+
+const (
+	// PlanInvalidPrefix is a network which is missing or has a
+	// non-contiguous mask.  Such networks are not checked further.
+	PlanInvalidPrefix PlanIssueKind = iota
+
+	// PlanHostBits is a network with host bits set in its address.
+	PlanHostBits
+
+	// PlanOutsideParent is a network not contained in the parent network.
+	PlanOutsideParent
+
+	// PlanOverlap is a network overlapping with another one of the plan.
+	PlanOverlap
+
+	// PlanDuplicateName is a network with the name of another one.
+	PlanDuplicateName
+)
+
+func (k PlanIssueKind) String() string {
+	switch k {
+	case PlanInvalidPrefix:
+		return "invalid prefix"
+	case PlanHostBits:
+		return "host bits set"
+	case PlanOutsideParent:
+		return "outside of parent"
+	case PlanOverlap:
+		return "overlap"
+	case PlanDuplicateName:
+		return "duplicate name"
+	}
+	return fmt.Sprintf("PlanIssueKind(%d)", int(k))
+}
+
+// PlanIssue is a problem with a network of an address plan.
+type PlanIssue struct {
+	Kind PlanIssueKind
+
+	// Index is the position of the network in the plan
+	Index int
+
+	// Other is the position of the conflicting network for overlaps and
+	// duplicate names, -1 for other issues
+	Other int
+}
+
+// PlanReport is the result of ValidatePlan.
+type PlanReport struct {
+	Subnets []NamedPrefix
+
+	// Issues are sorted by Index, then by Kind
+	Issues []PlanIssue
+}
+
+// OK reports whether no issues were found.
+func (r *PlanReport) OK() bool {
+	return len(r.Issues) == 0
+}
+
+// Messages returns the issues in a human readable form, one per issue.
+func (r *PlanReport) Messages() []string {
+	result := make([]string, len(r.Issues))
+	for i, issue := range r.Issues {
+		result[i] = fmt.Sprintf("%v: %v", r.Subnets[issue.Index], issue.Kind)
+		if issue.Other >= 0 {
+			result[i] += fmt.Sprintf(" with %v", r.Subnets[issue.Other])
+		}
+	}
+	return result
+}
+
+// ValidatePlan checks that the subnets form a valid address plan inside the
+// parent network: every subnet has a valid prefix without host bits set,
+// lies inside the parent, doesn't overlap with other subnets and has a
+// unique name.  An error is returned only if the parent network is not
+// valid.
+func ValidatePlan(parent *net.IPNet, subnets []NamedPrefix) (*PlanReport, error) {
+	parentPrefix, err := ToPrefix(parent)
+	if err != nil {
+		return nil, err
+	}
+	report := &PlanReport{Subnets: subnets}
+	addIssue := func(kind PlanIssueKind, index, other int) {
+		report.Issues = append(report.Issues, PlanIssue{Kind: kind, Index: index, Other: other})
+	}
+
+	type planEntry struct {
+		prefix netip.Prefix
+		index  int
+	}
+	var entries []planEntry
+	names := map[string]int{}
+	for i, subnet := range subnets {
+		if first, ok := names[subnet.Name]; ok {
+			addIssue(PlanDuplicateName, i, first)
+		} else {
+			names[subnet.Name] = i
+		}
+		prefix, err := ToPrefix(subnet.Network)
+		if err != nil {
+			addIssue(PlanInvalidPrefix, i, -1)
+			continue
+		}
+		if !subnet.Network.IP.Equal(subnet.Network.IP.Mask(subnet.Network.Mask)) {
+			addIssue(PlanHostBits, i, -1)
+		}
+		if prefix.Bits() < parentPrefix.Bits() || !parentPrefix.Contains(prefix.Addr()) {
+			addIssue(PlanOutsideParent, i, -1)
+		}
+		entries = append(entries, planEntry{prefix, i})
+	}
+
+	// with prefixes sorted by address and then by length, a prefix overlaps
+	// exactly with the earlier prefixes still containing it
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i].prefix, entries[j].prefix
+		if c := a.Addr().Compare(b.Addr()); c != 0 {
+			return c < 0
+		}
+		return a.Bits() < b.Bits()
+	})
+	var open []planEntry
+	for _, e := range entries {
+		for len(open) > 0 && !prefixContains(open[len(open)-1].prefix, e.prefix) {
+			open = open[:len(open)-1]
+		}
+		for _, o := range open {
+			addIssue(PlanOverlap, e.index, o.index)
+		}
+		open = append(open, e)
+	}
+
+	sort.SliceStable(report.Issues, func(i, j int) bool {
+		a, b := report.Issues[i], report.Issues[j]
+		if a.Index != b.Index {
+			return a.Index < b.Index
+		}
+		return a.Kind < b.Kind
+	})
+	return report, nil
+}
+
+// prefixContains reports whether the prefix a contains the prefix b
+func prefixContains(a, b netip.Prefix) bool {
+	return a.Bits() <= b.Bits() && a.Contains(b.Addr())
+}
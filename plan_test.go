@@ -0,0 +1,76 @@
+// SPDX-License-Identifier: MIT-0
+
+package iputils
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestValidatePlan(t *testing.T) {
+	subnets := []NamedPrefix{
+		NamedPrefix{"web", mustParseCIDR("10.0.0.0/24")},
+		NamedPrefix{"db", mustParseCIDR("10.0.1.0/24")},
+		NamedPrefix{"apps", mustParseCIDR("10.0.0.0/23")},
+		NamedPrefix{"web", mustParseCIDR("10.0.2.0/24")},
+		NamedPrefix{"dmz", &net.IPNet{IP: net.IP{10, 0, 3, 1}, Mask: net.CIDRMask(24, 32)}},
+		NamedPrefix{"legacy", mustParseCIDR("192.168.0.0/24")},
+		NamedPrefix{"broken", &net.IPNet{IP: net.IP{10, 0, 4, 0}, Mask: net.IPMask{255, 0, 255, 0}}},
+		NamedPrefix{"v6", mustParseCIDR("2001:db8::/64")},
+		NamedPrefix{"all", mustParseCIDR("10.0.0.0/8")},
+		NamedPrefix{"host", mustParseCIDR("10.0.1.7/32")},
+	}
+	report, err := ValidatePlan(mustParseCIDR("10.0.0.0/16"), subnets)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	expected := []PlanIssue{
+		PlanIssue{PlanOverlap, 0, 8},
+		PlanIssue{PlanOverlap, 0, 2},
+		PlanIssue{PlanOverlap, 1, 8},
+		PlanIssue{PlanOverlap, 1, 2},
+		PlanIssue{PlanOverlap, 2, 8},
+		PlanIssue{PlanOverlap, 3, 8},
+		PlanIssue{PlanDuplicateName, 3, 0},
+		PlanIssue{PlanHostBits, 4, -1},
+		PlanIssue{PlanOverlap, 4, 8},
+		PlanIssue{PlanOutsideParent, 5, -1},
+		PlanIssue{PlanInvalidPrefix, 6, -1},
+		PlanIssue{PlanOutsideParent, 7, -1},
+		PlanIssue{PlanOutsideParent, 8, -1},
+		PlanIssue{PlanOverlap, 9, 8},
+		PlanIssue{PlanOverlap, 9, 2},
+		PlanIssue{PlanOverlap, 9, 1},
+	}
+	if !reflect.DeepEqual(expected, report.Issues) {
+		t.Errorf("expecting issues\n%v\ngot\n%v", expected, report.Issues)
+	}
+	if report.OK() {
+		t.Errorf("report with issues is OK")
+	}
+
+	report, err = ValidatePlan(mustParseCIDR("10.0.0.0/16"), subnets[:2])
+	if err != nil || !report.OK() {
+		t.Errorf("expecting valid plan, got %v, %v", report.Messages(), err)
+	}
+	if _, err := ValidatePlan(nil, subnets); err == nil {
+		t.Errorf("didn't get an error for missing parent")
+	}
+}
+
+func ExamplePlanReport_Messages() {
+	_, parent, _ := net.ParseCIDR("10.0.0.0/16")
+	_, web, _ := net.ParseCIDR("10.0.0.0/24")
+	_, apps, _ := net.ParseCIDR("10.0.0.0/23")
+	_, legacy, _ := net.ParseCIDR("192.168.0.0/24")
+	report, _ := ValidatePlan(parent, []NamedPrefix{{"web", web}, {"apps", apps}, {"legacy", legacy}})
+	for _, message := range report.Messages() {
+		fmt.Println(message)
+	}
+
+	// Output:
+	// web (10.0.0.0/24): overlap with apps (10.0.0.0/23)
+	// legacy (192.168.0.0/24): outside of parent
+}
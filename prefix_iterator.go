@@ -0,0 +1,90 @@
+// SPDX-License-Identifier: MIT-0
+
+package iputils
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+)
+
+// PrefixIterator allows you to iterate over networks of the same prefix length
+type PrefixIterator interface {
+
+	// Next returns the next network and true if the next network exists.
+	// If it doesn't exist, nil and false are returned.
+	Next() (n *net.IPNet, ok bool)
+}
+
+// GetPrefixIterator returns an iterator over all networks with the given
+// prefix length which intersect the range of ip addresses from first to last
+// (inclusive).  The first network produced is the one containing first.
+//
+// If both addresses are IPv4, the prefix length is counted in the IPv4
+// address space, otherwise in the IPv6 one.  If the prefix length is out of
+// the address space, the iterator doesn't produce any networks.
+func GetPrefixIterator(first, last net.IP, prefixLen int) PrefixIterator {
+	f, l := first.To4(), last.To4()
+	if f == nil || l == nil {
+		f, l = first.To16(), last.To16()
+	}
+	iter := &prefixIterator{first: f, last: l, length: prefixLen}
+	if f == nil || l == nil || prefixLen < 0 || prefixLen > len(f)*8 || bytes.Compare(f, l) > 0 {
+		return iter
+	}
+	iter.next = f.Mask(net.CIDRMask(prefixLen, len(f)*8))
+	return iter
+}
+
+// GetPrefix64Iterator returns an iterator over all /64 IPv6 networks which
+// intersect the range of ip addresses from first to last (inclusive).
+func GetPrefix64Iterator(first, last net.IP) PrefixIterator {
+	return GetPrefixIterator(first.To16(), last.To16(), 64)
+}
+
+type prefixIterator struct {
+	first  net.IP
+	last   net.IP
+	length int
+
+	// next is nil when the iteration is over
+	next net.IP
+}
+
+func (iter *prefixIterator) Next() (n *net.IPNet, ok bool) {
+	if iter.next == nil {
+		return nil, false
+	}
+	n = &net.IPNet{IP: CopyIP(iter.next), Mask: net.CIDRMask(iter.length, len(iter.next)*8)}
+	if !addPrefixBlock(iter.next, iter.length) || bytes.Compare(iter.next, iter.last) > 0 {
+		iter.next = nil
+	}
+	return n, true
+}
+
+func (iter *prefixIterator) String() string {
+	if iter.next == nil {
+		return fmt.Sprintf("PrefixIterator(%v -> %v /%v, next: none)", iter.first, iter.last, iter.length)
+	}
+	return fmt.Sprintf("PrefixIterator(%v -> %v /%v, next: %v/%v)", iter.first, iter.last, iter.length, iter.next, iter.length)
+}
+
+// addPrefixBlock advances ip by the size of a network with the given prefix
+// length.  If the result doesn't fit into the address space, false is
+// returned.
+func addPrefixBlock(ip net.IP, prefixLen int) bool {
+	if prefixLen <= 0 {
+		return false
+	}
+	i := (prefixLen - 1) / 8
+	inc := uint(1) << (7 - uint((prefixLen-1)%8))
+	for ; i >= 0; i-- {
+		sum := uint(ip[i]) + inc
+		ip[i] = byte(sum)
+		if sum <= 0xff {
+			return true
+		}
+		inc = 1
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+// SPDX-License-Identifier: MIT-0
+
+package iputils
+
+import (
+	"fmt"
+	"net"
+	"testing"
+)
+
+func TestPrefixIterator(t *testing.T) {
+	type testCase struct {
+		first    net.IP
+		last     net.IP
+		length   int
+		sequence []string
+	}
+	cases := []testCase{
+		testCase{net.ParseIP("192.168.0.10"), net.ParseIP("192.168.2.1"), 24,
+			[]string{"192.168.0.0/24", "192.168.1.0/24", "192.168.2.0/24"}},
+		testCase{net.ParseIP("192.168.0.10"), net.ParseIP("192.168.0.11"), 24, []string{"192.168.0.0/24"}},
+		testCase{net.ParseIP("192.168.0.10"), net.ParseIP("192.168.0.13"), 31,
+			[]string{"192.168.0.10/31", "192.168.0.12/31"}},
+		testCase{net.ParseIP("255.255.255.0"), net.ParseIP("255.255.255.255"), 25,
+			[]string{"255.255.255.0/25", "255.255.255.128/25"}},
+		testCase{net.ParseIP("10.0.0.0"), net.ParseIP("10.0.0.1"), 0, []string{"0.0.0.0/0"}},
+		testCase{net.ParseIP("10.0.0.0"), net.ParseIP("10.0.0.1"), 33, []string{}},
+		testCase{net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.0"), 32, []string{}},
+		testCase{net.ParseIP("2001:db8:0:ffff::1"), net.ParseIP("2001:db8:1:1::"), 48,
+			[]string{"2001:db8::/48", "2001:db8:1::/48"}},
+		testCase{net.ParseIP("ffff:ffff:ffff:fffe::"), net.ParseIP("ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff"), 64,
+			[]string{"ffff:ffff:ffff:fffe::/64", "ffff:ffff:ffff:ffff::/64"}},
+	}
+NEXT_CASE:
+	for _, test := range cases {
+		iter := GetPrefixIterator(test.first, test.last, test.length)
+		for i := 0; i < len(test.sequence); i++ {
+			value, ok := iter.Next()
+			if !ok {
+				t.Errorf("iterator %v has not produced enough values; expecting sequence %v", iter, test.sequence)
+				continue NEXT_CASE
+			}
+			if test.sequence[i] != value.String() {
+				t.Errorf("iteration %v of %v produced %v, expecting %v", i+1, iter, value, test.sequence[i])
+				continue NEXT_CASE
+			}
+		}
+		if value, ok := iter.Next(); ok {
+			t.Errorf("iterator %v has produced more values than expected: %v", iter, value)
+		}
+	}
+}
+
+func TestPrefix64Iterator(t *testing.T) {
+	iter := GetPrefix64Iterator(net.ParseIP("2001:db8::ffff"), net.ParseIP("2001:db8:0:2::1"))
+	expected := []string{"2001:db8::/64", "2001:db8:0:1::/64", "2001:db8:0:2::/64"}
+	for i := 0; i < len(expected); i++ {
+		value, ok := iter.Next()
+		if !ok || value.String() != expected[i] {
+			t.Fatalf("iteration %v produced (%v, %v), expecting %v", i+1, value, ok, expected[i])
+		}
+	}
+	if value, ok := iter.Next(); ok || value != nil {
+		t.Errorf("expecting (<nil>, false), got (%v, %v)", value, ok)
+	}
+}
+
+func ExampleGetPrefix64Iterator() {
+	iter := GetPrefix64Iterator(net.ParseIP("2001:db8::1"), net.ParseIP("2001:db8:0:2::1"))
+	for n, ok := iter.Next(); ok; n, ok = iter.Next() {
+		fmt.Println(n)
+	}
+
+	// Output:
+	// 2001:db8::/64
+	// 2001:db8:0:1::/64
+	// 2001:db8:0:2::/64
+}
@@ -0,0 +1,415 @@
+// SPDX-License-Identifier: MIT-0
+
+package iputils
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"net"
+	"sort"
+	"strings"
+)
+
+// IPRange describes a range of ip addresses from First to Last.  The last
+// address is included into the range.
+type IPRange struct {
+	First net.IP
+	Last  net.IP
+}
+
+func (r IPRange) String() string {
+	return fmt.Sprintf("%v-%v", r.First, r.Last)
+}
+
+// Contains reports whether ip belongs to the range.
+func (r IPRange) Contains(ip net.IP) bool {
+	return Compare(r.First, ip) <= 0 && Compare(ip, r.Last) <= 0
+}
+
+// ParseIPRange parses a range of ip addresses written as two addresses of
+// the same family separated by a dash, e.g. "192.168.0.10-192.168.0.20".
+// IPv4 addresses are returned in the 4-byte form.
+func ParseIPRange(s string) (IPRange, error) {
+	parts := strings.Split(s, "-")
+	if len(parts) != 2 {
+		return IPRange{}, fmt.Errorf("%w %q", ErrInvalidRange, s)
+	}
+	first := parseIPNormalized(strings.TrimSpace(parts[0]))
+	last := parseIPNormalized(strings.TrimSpace(parts[1]))
+	if first == nil || last == nil {
+		return IPRange{}, fmt.Errorf("%w %q", ErrInvalidRange, s)
+	}
+	if len(first) != len(last) {
+		return IPRange{}, fmt.Errorf("%w %q: %w", ErrInvalidRange, s, ErrFamilyMismatch)
+	}
+	if bytes.Compare(first, last) > 0 {
+		return IPRange{}, fmt.Errorf("%w %q: the first address is bigger than the last one", ErrInvalidRange, s)
+	}
+	return IPRange{first, last}, nil
+}
+
+// RangeIsCIDR returns the network consisting exactly of the addresses from
+// first to last and true.  If the range can't be expressed as a single
+// network, nil and false are returned.
+func RangeIsCIDR(first, last net.IP) (*net.IPNet, bool) {
+	f, l := first.To4(), last.To4()
+	if f == nil || l == nil {
+		f, l = first.To16(), last.To16()
+	}
+	if f == nil || l == nil || (first.To4() == nil) != (last.To4() == nil) {
+		return nil, false
+	}
+	bits := len(f) * 8
+	ones := 0
+	for ones < bits && keyBit(f, ones) == keyBit(l, ones) {
+		ones++
+	}
+	for i := ones; i < bits; i++ {
+		if keyBit(f, i) != 0 || keyBit(l, i) != 1 {
+			return nil, false
+		}
+	}
+	return &net.IPNet{IP: CopyIP(f), Mask: net.CIDRMask(ones, bits)}, true
+}
+
+// ShiftRange moves the range by offset addresses (negative offsets move it
+// down).  An error is returned if the range is not valid or the result
+// doesn't fit into the address space of the range family.
+func ShiftRange(r IPRange, offset *big.Int) (IPRange, error) {
+	n := normalizeRange(r)
+	if n.First == nil || len(n.First) != len(n.Last) {
+		return IPRange{}, fmt.Errorf("%w %v", ErrInvalidRange, r)
+	}
+	size := len(n.First)
+	first := ipToInt(n.First)
+	first.Add(first, offset)
+	last := ipToInt(n.Last)
+	last.Add(last, offset)
+	if first.Sign() < 0 || last.BitLen() > size*8 {
+		return IPRange{}, fmt.Errorf("%w: IP range %v shifted by %v", ErrOverflow, r, offset)
+	}
+	return IPRange{intToIP(first, size), intToIP(last, size)}, nil
+}
+
+// ShiftPrefix moves the network by the given number of networks of the same
+// size, e.g. shifting 192.168.1.0/24 by 1 gives 192.168.2.0/24.  An error is
+// returned if the network is not valid or the result doesn't fit into the
+// address space.
+func ShiftPrefix(n *net.IPNet, blocks *big.Int) (*net.IPNet, error) {
+	first, size, err := networkFirstAndSize(n)
+	if err != nil {
+		return nil, err
+	}
+	value := new(big.Int).Mul(size, blocks)
+	value.Add(value, ipToInt(first))
+	last := new(big.Int).Add(value, size)
+	last.Sub(last, big.NewInt(1))
+	if value.Sign() < 0 || last.BitLen() > len(first)*8 {
+		return nil, fmt.Errorf("%w: network %v shifted by %v", ErrOverflow, n, blocks)
+	}
+	return &net.IPNet{IP: intToIP(value, len(first)), Mask: append(net.IPMask(nil), n.Mask...)}, nil
+}
+
+// Neighborhood returns the range of addresses at most radius away from ip,
+// clamped to the address space of its family.  IPv4 addresses produce a
+// range of 4-byte addresses.  A negative radius is taken as 0.  If ip is not
+// valid, the zero IPRange is returned.
+func Neighborhood(ip net.IP, radius int) IPRange {
+	ip = normalizeRange(IPRange{ip, ip}).First
+	if ip == nil {
+		return IPRange{}
+	}
+	high := new(big.Int).Lsh(big.NewInt(1), uint(len(ip)*8))
+	return neighborhood(ip, radius, new(big.Int), high.Sub(high, big.NewInt(1)))
+}
+
+// NeighborhoodWithin works like Neighborhood, but clamps the range to the
+// network, e.g. a big radius gives the whole network.  The addresses have
+// the size of the network address.  If ip doesn't belong to the network or
+// the network is not valid, the zero IPRange is returned.
+func NeighborhoodWithin(ip net.IP, radius int, n *net.IPNet) IPRange {
+	first, size, err := networkFirstAndSize(n)
+	if err != nil || !n.Contains(ip) {
+		return IPRange{}
+	}
+	if len(first) == IPv4Size {
+		ip = ip.To4()
+	} else {
+		ip = ip.To16()
+	}
+	low := ipToInt(first)
+	high := new(big.Int).Add(low, size)
+	return neighborhood(ip, radius, low, high.Sub(high, big.NewInt(1)))
+}
+
+// neighborhood returns the range of addresses at most radius away from ip
+// clamped to the values from low to high
+func neighborhood(ip net.IP, radius int, low, high *big.Int) IPRange {
+	if radius < 0 {
+		radius = 0
+	}
+	value := ipToInt(ip)
+	first := new(big.Int).Sub(value, big.NewInt(int64(radius)))
+	if first.Cmp(low) < 0 {
+		first = low
+	}
+	last := value.Add(value, big.NewInt(int64(radius)))
+	if last.Cmp(high) > 0 {
+		last = high
+	}
+	return IPRange{intToIP(first, len(ip)), intToIP(last, len(ip))}
+}
+
+// Midpoint returns the address in the middle of the range, rounding down
+// for ranges with an even number of addresses.  If the range is not valid,
+// nil is returned.
+func Midpoint(r IPRange) net.IP {
+	return Quantile(r, 1, 2)
+}
+
+// Quantile returns the address num/den of the way from the first address of
+// the range to the last one, rounding down: 0/den gives the first address,
+// den/den gives the last one.  If the range is not valid or the fraction is
+// not between 0 and 1, nil is returned.
+func Quantile(r IPRange, num, den int) net.IP {
+	r = normalizeRange(r)
+	if r.First == nil || den <= 0 || num < 0 || num > den {
+		return nil
+	}
+	first := ipToInt(r.First)
+	offset := new(big.Int).Sub(ipToInt(r.Last), first)
+	offset.Mul(offset, big.NewInt(int64(num)))
+	offset.Quo(offset, big.NewInt(int64(den)))
+	return intToIP(first.Add(first, offset), len(r.First))
+}
+
+// Intersect returns the addresses belonging to both ranges and true.  If the
+// ranges don't overlap or any of them is not valid, false is returned.
+func Intersect(a, b IPRange) (IPRange, bool) {
+	a, b = normalizeRange(a), normalizeRange(b)
+	if a.First == nil || b.First == nil {
+		return IPRange{}, false
+	}
+	r := a
+	if Compare(b.First, r.First) > 0 {
+		r.First = b.First
+	}
+	if Compare(b.Last, r.Last) < 0 {
+		r.Last = b.Last
+	}
+	if Compare(r.First, r.Last) > 0 {
+		return IPRange{}, false
+	}
+	return normalizeRange(r), true
+}
+
+// IntersectMany returns the addresses belonging to both lists of ranges.
+// The lists don't have to be sorted and may contain overlapping ranges; the
+// result is sorted, with overlapping and adjacent ranges joined.
+func IntersectMany(a, b []IPRange) []IPRange {
+	var sa, sb IPSet
+	for _, r := range a {
+		sa.AddRange(r)
+	}
+	for _, r := range b {
+		sb.AddRange(r)
+	}
+	var result []IPRange
+	for i, j := 0, 0; i < len(sa.ranges) && j < len(sb.ranges); {
+		if r, ok := Intersect(sa.ranges[i], sb.ranges[j]); ok {
+			result = append(result, r)
+		}
+		if Compare(sa.ranges[i].Last, sb.ranges[j].Last) < 0 {
+			i++
+		} else {
+			j++
+		}
+	}
+	return result
+}
+
+// SubtractAll returns the addresses of base which don't belong to any of the
+// excluded networks, as a sorted list of ranges.  The exclusions are sorted
+// once and swept in a single pass, so the cost doesn't depend on how many
+// of them overlap base.  Exclusions with non-contiguous masks are ignored.
+func SubtractAll(base IPRange, excludes []*net.IPNet) []IPRange {
+	ranges := make([]IPRange, 0, len(excludes))
+	for _, n := range excludes {
+		first, size, err := networkFirstAndSize(n)
+		if err != nil {
+			continue
+		}
+		last := ipToInt(first)
+		last.Add(last, size)
+		last.Sub(last, big.NewInt(1))
+		ranges = append(ranges, normalizeRange(IPRange{first, intToIP(last, len(first))}))
+	}
+	sort.Slice(ranges, func(i, j int) bool { return Compare(ranges[i].First, ranges[j].First) < 0 })
+
+	var result []IPRange
+	for _, part := range splitRangeByFamily(normalizeRange(base)) {
+		result = append(result, subtractSorted(part, ranges)...)
+	}
+	return result
+}
+
+// FindGaps returns the free space of the parent network: the addresses
+// which don't belong to any of the used networks, as a sorted list of ranges
+// with adjacent free addresses joined.  If the parent network is not valid,
+// nil is returned.
+func FindGaps(parent *net.IPNet, used []*net.IPNet) []IPRange {
+	first, size, err := networkFirstAndSize(parent)
+	if err != nil {
+		return nil
+	}
+	last := ipToInt(first)
+	last.Add(last, size)
+	last.Sub(last, big.NewInt(1))
+	return SubtractAll(IPRange{first, intToIP(last, len(first))}, used)
+}
+
+// subtractSorted removes the ranges sorted by their first address from the
+// normalized range of one family.
+func subtractSorted(base IPRange, ranges []IPRange) []IPRange {
+	var result []IPRange
+	next := CopyIP(base.First)
+	for _, r := range ranges {
+		if Compare(r.First, base.Last) > 0 {
+			break
+		}
+		r, ok := Intersect(base, r)
+		if !ok || Compare(r.Last, next) < 0 {
+			continue
+		}
+		if Compare(r.First, next) > 0 {
+			last := CopyIP(r.First)
+			Prev(last)
+			result = append(result, IPRange{next, last})
+		}
+		next = CopyIP(r.Last)
+		if !Next(next) || Compare(next, base.Last) > 0 {
+			return result
+		}
+	}
+	return append(result, IPRange{next, CopyIP(base.Last)})
+}
+
+// rangeToNetworks returns the smallest list of networks covering exactly the
+// addresses of the normalized range of one family.
+func rangeToNetworks(r IPRange) []*net.IPNet {
+	size := len(r.First)
+	bits := size * 8
+	first, last := ipToInt(r.First), ipToInt(r.Last)
+	one := big.NewInt(1)
+	var result []*net.IPNet
+	for first.Cmp(last) <= 0 {
+		// the biggest block aligned at first which doesn't pass last
+		hostBits := bits
+		if first.Sign() != 0 {
+			hostBits = int(first.TrailingZeroBits())
+		}
+		count := new(big.Int).Sub(last, first)
+		count.Add(count, one)
+		if limit := count.BitLen() - 1; limit < hostBits {
+			hostBits = limit
+		}
+		result = append(result, &net.IPNet{IP: intToIP(first, size), Mask: net.CIDRMask(bits-hostBits, bits)})
+		first.Add(first, new(big.Int).Lsh(one, uint(hostBits)))
+	}
+	return result
+}
+
+// ElementKind describes what kind of value an Element holds
+type ElementKind int
+
+const (
+	// ElementIP is a single ip address
+	ElementIP ElementKind = iota + 1
+
+	// ElementNetwork is a network in CIDR notation
+	ElementNetwork
+
+	// ElementRange is a range of ip addresses
+	ElementRange
+)
+
+func (k ElementKind) String() string {
+	switch k {
+	case ElementIP:
+		return "ip"
+	case ElementNetwork:
+		return "network"
+	case ElementRange:
+		return "range"
+	}
+	return fmt.Sprintf("ElementKind(%d)", int(k))
+}
+
+// Element is a single ip address, a network or a range of ip addresses as
+// returned by ParseAny.
+type Element struct {
+	Kind ElementKind
+
+	// IP is the address of an ElementIP or the address written in the CIDR
+	// notation of an ElementNetwork
+	IP net.IP
+
+	// Network is set for ElementNetwork
+	Network *net.IPNet
+
+	// Range contains the addresses covered by the element regardless of its kind
+	Range IPRange
+}
+
+func (e Element) String() string {
+	switch e.Kind {
+	case ElementIP:
+		return e.IP.String()
+	case ElementNetwork:
+		return e.Network.String()
+	}
+	return e.Range.String()
+}
+
+// ParseAny parses a single ip address ("192.168.0.1"), a network in CIDR
+// notation ("192.168.0.0/24") or a range of ip addresses
+// ("192.168.0.1-192.168.0.5").  IPv4 addresses are returned in the 4-byte
+// form.
+func ParseAny(s string) (Element, error) {
+	s = strings.TrimSpace(s)
+	switch {
+	case strings.Contains(s, "-"):
+		r, err := ParseIPRange(s)
+		if err != nil {
+			return Element{}, err
+		}
+		return Element{Kind: ElementRange, Range: r}, nil
+
+	case strings.Contains(s, "/"):
+		ip, n, err := net.ParseCIDR(s)
+		if err != nil {
+			return Element{}, fmt.Errorf("%w %q", ErrInvalidNetwork, s)
+		}
+		if ip4 := ip.To4(); ip4 != nil {
+			ip = ip4
+		}
+		first, last := GetNetworkIPRange(n)
+		return Element{Kind: ElementNetwork, IP: ip, Network: n, Range: IPRange{first, last}}, nil
+	}
+
+	ip := parseIPNormalized(s)
+	if ip == nil {
+		return Element{}, fmt.Errorf("%w %q", ErrInvalidIP, s)
+	}
+	return Element{Kind: ElementIP, IP: ip, Range: IPRange{ip, CopyIP(ip)}}, nil
+}
+
+// parseIPNormalized parses an ip address returning IPv4 in the 4-byte form
+func parseIPNormalized(s string) net.IP {
+	ip := net.ParseIP(s)
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4
+	}
+	return ip
+}
@@ -0,0 +1,415 @@
+// SPDX-License-Identifier: MIT-0
+
+package iputils
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+	"testing"
+)
+
+func TestParseIPRange(t *testing.T) {
+	type testCase struct {
+		input string
+		first net.IP
+		last  net.IP
+	}
+	cases := []testCase{
+		testCase{"192.168.0.1-192.168.0.5", net.IP{192, 168, 0, 1}, net.IP{192, 168, 0, 5}},
+		testCase{" 192.168.0.1 - 192.168.0.1 ", net.IP{192, 168, 0, 1}, net.IP{192, 168, 0, 1}},
+		testCase{"::1-::ffff", net.ParseIP("::1"), net.ParseIP("::ffff")},
+	}
+	for _, test := range cases {
+		r, err := ParseIPRange(test.input)
+		if err != nil {
+			t.Errorf("unexpected error %v when parsing %q", err, test.input)
+			continue
+		}
+		if !test.first.Equal(r.First) || !test.last.Equal(r.Last) || len(test.first) != len(r.First) {
+			t.Errorf("expecting %v-%v, got %v when parsing %q", test.first, test.last, r, test.input)
+		}
+	}
+}
+
+func TestParseIPRangeFaults(t *testing.T) {
+	faultCases := []string{
+		"",
+		"192.168.0.1",
+		"192.168.0.1-",
+		"192.168.0.1-192.168.0.2-192.168.0.3",
+		"192.168.0.5-192.168.0.1",
+		"192.168.0.1-::1",
+		"192.168.0.1-foo",
+	}
+	for _, test := range faultCases {
+		if r, err := ParseIPRange(test); err == nil {
+			t.Errorf("didn't get an error when parsing %q, got %v", test, r)
+		}
+	}
+}
+
+func TestIPRangeContains(t *testing.T) {
+	r := IPRange{net.IP{10, 0, 0, 10}, net.IP{10, 0, 0, 20}}
+	type testCase struct {
+		ip     net.IP
+		result bool
+	}
+	cases := []testCase{
+		testCase{net.ParseIP("10.0.0.10"), true},
+		testCase{net.ParseIP("10.0.0.15"), true},
+		testCase{net.IP{10, 0, 0, 20}, true},
+		testCase{net.ParseIP("10.0.0.9"), false},
+		testCase{net.ParseIP("10.0.0.21"), false},
+		testCase{net.ParseIP("::1"), false},
+	}
+	for _, test := range cases {
+		if result := r.Contains(test.ip); test.result != result {
+			t.Errorf("expecting %v, got %v for %v in %v", test.result, result, test.ip, r)
+		}
+	}
+}
+
+func TestRangeIsCIDR(t *testing.T) {
+	type testCase struct {
+		first   net.IP
+		last    net.IP
+		network string
+	}
+	cases := []testCase{
+		testCase{net.ParseIP("192.168.0.0"), net.ParseIP("192.168.0.255"), "192.168.0.0/24"},
+		testCase{net.ParseIP("192.168.0.4"), net.IP{192, 168, 0, 7}, "192.168.0.4/30"},
+		testCase{net.ParseIP("192.168.0.4"), net.ParseIP("192.168.0.4"), "192.168.0.4/32"},
+		testCase{net.ParseIP("0.0.0.0"), net.ParseIP("255.255.255.255"), "0.0.0.0/0"},
+		testCase{net.ParseIP("2001:db8::"), net.ParseIP("2001:db8::ffff:ffff:ffff:ffff"), "2001:db8::/64"},
+		testCase{net.ParseIP("::"), net.ParseIP("ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff"), "::/0"},
+		testCase{net.ParseIP("192.168.0.1"), net.ParseIP("192.168.0.255"), ""},
+		testCase{net.ParseIP("192.168.0.0"), net.ParseIP("192.168.0.254"), ""},
+		testCase{net.ParseIP("192.168.0.2"), net.ParseIP("192.168.0.5"), ""},
+		testCase{net.ParseIP("192.168.0.5"), net.ParseIP("192.168.0.2"), ""},
+		testCase{net.ParseIP("::ffff:0:0"), net.ParseIP("::ffff:ffff:ffff"), "0.0.0.0/0"},
+		testCase{net.ParseIP("::"), net.ParseIP("255.255.255.255"), ""},
+		testCase{nil, net.ParseIP("::1"), ""},
+	}
+	for _, test := range cases {
+		network, ok := RangeIsCIDR(test.first, test.last)
+		if ok != (test.network != "") || (ok && test.network != network.String()) {
+			t.Errorf("expecting %q, got (%v, %v) for %v-%v", test.network, network, ok, test.first, test.last)
+		}
+	}
+}
+
+func TestShiftRange(t *testing.T) {
+	type testCase struct {
+		r      string
+		offset int64
+		result string
+	}
+	cases := []testCase{
+		testCase{"10.0.0.0-10.0.0.9", 10, "10.0.0.10-10.0.0.19"},
+		testCase{"10.0.1.0/24", -256, "10.0.0.0-10.0.0.255"},
+		testCase{"10.0.0.0-10.0.0.9", 0, "10.0.0.0-10.0.0.9"},
+		testCase{"255.255.255.0/25", 128, "255.255.255.128-255.255.255.255"},
+		testCase{"0.0.0.10", -10, "0.0.0.0-0.0.0.0"},
+		testCase{"2001:db8::/64", 1 << 62, "2001:db8:0:0:4000::-2001:db8:0:1:3fff:ffff:ffff:ffff"},
+		testCase{"255.255.255.0/25", 129, ""},
+		testCase{"0.0.0.10", -11, ""},
+		testCase{"ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff", 1, ""},
+	}
+	for _, test := range cases {
+		r := mustParseRanges(test.r)[0]
+		result, err := ShiftRange(r, big.NewInt(test.offset))
+		if (err == nil) != (test.result != "") || (err == nil && test.result != result.String()) {
+			t.Errorf("expecting %q, got (%v, %v) when shifting %v by %v", test.result, result, err, r, test.offset)
+		}
+	}
+	if result, err := ShiftRange(IPRange{net.ParseIP("10.0.0.9"), net.ParseIP("10.0.0.0")}, big.NewInt(1)); err == nil {
+		t.Errorf("didn't get an error when shifting invalid range, got %v", result)
+	}
+}
+
+func TestShiftPrefix(t *testing.T) {
+	type testCase struct {
+		network string
+		blocks  int64
+		result  string
+	}
+	cases := []testCase{
+		testCase{"192.168.1.0/24", 1, "192.168.2.0/24"},
+		testCase{"192.168.1.0/24", -1, "192.168.0.0/24"},
+		testCase{"192.168.1.77/24", 0, "192.168.1.0/24"},
+		testCase{"255.255.254.0/24", 1, "255.255.255.0/24"},
+		testCase{"2001:db8::/48", 0x10, "2001:db8:10::/48"},
+		testCase{"255.255.255.0/24", 1, ""},
+		testCase{"0.0.1.0/24", -2, ""},
+		testCase{"0.0.0.0/0", 1, ""},
+	}
+	for _, test := range cases {
+		n := mustParseCIDR(test.network)
+		result, err := ShiftPrefix(n, big.NewInt(test.blocks))
+		if (err == nil) != (test.result != "") || (err == nil && test.result != result.String()) {
+			t.Errorf("expecting %q, got (%v, %v) when shifting %v by %v", test.result, result, err, n, test.blocks)
+		}
+	}
+}
+
+func TestNeighborhood(t *testing.T) {
+	type testCase struct {
+		ip     net.IP
+		radius int
+		result string
+	}
+	cases := []testCase{
+		testCase{net.ParseIP("192.0.2.100"), 10, "192.0.2.90-192.0.2.110"},
+		testCase{net.ParseIP("192.0.2.250"), 10, "192.0.2.240-192.0.3.4"},
+		testCase{net.IP{0, 0, 0, 3}, 10, "0.0.0.0-0.0.0.13"},
+		testCase{net.ParseIP("255.255.255.250"), 10, "255.255.255.240-255.255.255.255"},
+		testCase{net.ParseIP("192.0.2.1"), 0, "192.0.2.1-192.0.2.1"},
+		testCase{net.ParseIP("192.0.2.1"), -5, "192.0.2.1-192.0.2.1"},
+		testCase{net.ParseIP("2001:db8::1"), 16, "2001:db7:ffff:ffff:ffff:ffff:ffff:fff1-2001:db8::11"},
+		testCase{net.ParseIP("::5"), 10, "::-::f"},
+		testCase{net.ParseIP("ffff:ffff:ffff:ffff:ffff:ffff:ffff:fff0"), 100, "ffff:ffff:ffff:ffff:ffff:ffff:ffff:ff8c-ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff"},
+		testCase{nil, 10, "<nil>-<nil>"},
+	}
+	for _, test := range cases {
+		if result := Neighborhood(test.ip, test.radius).String(); result != test.result {
+			t.Errorf("expecting %v, got %v for %v and %v", test.result, result, test.ip, test.radius)
+		}
+	}
+}
+
+func TestNeighborhoodWithin(t *testing.T) {
+	type testCase struct {
+		ip      net.IP
+		radius  int
+		network string
+		result  string
+	}
+	cases := []testCase{
+		testCase{net.ParseIP("192.0.2.20"), 1000, "192.0.2.16/28", "192.0.2.16-192.0.2.31"},
+		testCase{net.ParseIP("192.0.2.20"), 2, "192.0.2.16/28", "192.0.2.18-192.0.2.22"},
+		testCase{net.IP{192, 0, 2, 30}, 5, "192.0.2.16/28", "192.0.2.25-192.0.2.31"},
+		testCase{net.ParseIP("2001:db8::1"), 1 << 20, "2001:db8::/120", "2001:db8::-2001:db8::ff"},
+		testCase{net.ParseIP("192.0.2.40"), 5, "192.0.2.16/28", "<nil>-<nil>"},
+		testCase{net.ParseIP("2001:db8::1"), 5, "192.0.2.16/28", "<nil>-<nil>"},
+	}
+	for _, test := range cases {
+		result := NeighborhoodWithin(test.ip, test.radius, mustParseCIDR(test.network)).String()
+		if result != test.result {
+			t.Errorf("expecting %v, got %v for %v, %v and %v", test.result, result, test.ip, test.radius, test.network)
+		}
+	}
+	if r := NeighborhoodWithin(net.ParseIP("10.0.0.1"), 5, nil); r.First != nil {
+		t.Errorf("expecting empty range for missing network, got %v", r)
+	}
+}
+
+func TestQuantile(t *testing.T) {
+	type testCase struct {
+		r      string
+		num    int
+		den    int
+		result string
+	}
+	cases := []testCase{
+		testCase{"10.0.0.0-10.0.0.255", 1, 2, "10.0.0.127"},
+		testCase{"10.0.0.0-10.0.0.255", 0, 10, "10.0.0.0"},
+		testCase{"10.0.0.0-10.0.0.255", 10, 10, "10.0.0.255"},
+		testCase{"10.0.0.0-10.0.0.100", 3, 10, "10.0.0.30"},
+		testCase{"10.0.0.0-10.0.0.255", 3, 10, "10.0.0.76"},
+		testCase{"10.0.0.7-10.0.0.7", 1, 2, "10.0.0.7"},
+		testCase{"0.0.0.0-255.255.255.255", 1, 4, "63.255.255.255"},
+		testCase{"::-ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff", 1, 2, "7fff:ffff:ffff:ffff:ffff:ffff:ffff:ffff"},
+		testCase{"2001:db8::-2001:db8::ffff", 9, 10, "2001:db8::e665"},
+		testCase{"10.0.0.0-10.0.0.255", 3, 2, "<nil>"},
+		testCase{"10.0.0.0-10.0.0.255", -1, 2, "<nil>"},
+		testCase{"10.0.0.0-10.0.0.255", 1, 0, "<nil>"},
+	}
+	for _, test := range cases {
+		ip := Quantile(mustParseRanges(test.r)[0], test.num, test.den)
+		if ip.String() != test.result {
+			t.Errorf("expecting %v, got %v for %v/%v of %v", test.result, ip, test.num, test.den, test.r)
+		}
+	}
+	if ip := Midpoint(IPRange{net.ParseIP("::ffff:10.0.0.0"), net.ParseIP("10.0.0.3")}); len(ip) != IPv4Size || ip.String() != "10.0.0.1" {
+		t.Errorf("unexpected midpoint %#v", ip)
+	}
+	if ip := Midpoint(IPRange{net.ParseIP("10.0.0.3"), net.ParseIP("10.0.0.0")}); ip != nil {
+		t.Errorf("expecting nil for reversed range, got %v", ip)
+	}
+}
+
+func TestIntersect(t *testing.T) {
+	type testCase struct {
+		a      IPRange
+		b      IPRange
+		result string
+	}
+	cases := []testCase{
+		testCase{mustParseRanges("10.0.0.0/24")[0], mustParseRanges("10.0.0.128-10.0.1.5")[0], "10.0.0.128-10.0.0.255"},
+		testCase{mustParseRanges("10.0.0.0/24")[0], mustParseRanges("10.0.0.5-10.0.0.6")[0], "10.0.0.5-10.0.0.6"},
+		testCase{mustParseRanges("10.0.0.0/24")[0], mustParseRanges("10.0.0.255-10.0.1.5")[0], "10.0.0.255-10.0.0.255"},
+		testCase{mustParseRanges("10.0.0.0/24")[0], mustParseRanges("10.0.1.0-10.0.1.5")[0], ""},
+		testCase{mustParseRanges("10.0.0.0/24")[0], mustParseRanges("::/0")[0], "10.0.0.0-10.0.0.255"},
+		testCase{IPRange{net.ParseIP("10.0.0.0"), net.ParseIP("10.0.0.9")}, mustParseRanges("10.0.0.5")[0], "10.0.0.5-10.0.0.5"},
+		testCase{mustParseRanges("10.0.0.0/24")[0], mustParseRanges("2001:db8::/32")[0], ""},
+		testCase{mustParseRanges("10.0.0.0/24")[0], IPRange{}, ""},
+	}
+	for _, test := range cases {
+		result, ok := Intersect(test.a, test.b)
+		if ok != (test.result != "") || (ok && test.result != result.String()) {
+			t.Errorf("expecting %q, got (%v, %v) when intersecting %v and %v", test.result, result, ok, test.a, test.b)
+		}
+	}
+}
+
+func TestIntersectMany(t *testing.T) {
+	type testCase struct {
+		a      []IPRange
+		b      []IPRange
+		result string
+	}
+	cases := []testCase{
+		testCase{nil, mustParseRanges("10.0.0.0/8"), "[]"},
+		testCase{
+			mustParseRanges("10.0.0.0/24", "10.0.2.0/24", "2001:db8::/64"),
+			mustParseRanges("10.0.0.100-10.0.2.10", "10.0.2.20", "2001:db8::5"),
+			"[10.0.0.100-10.0.0.255 10.0.2.0-10.0.2.10 10.0.2.20-10.0.2.20 2001:db8::5-2001:db8::5]",
+		},
+		testCase{
+			mustParseRanges("10.0.2.0/24", "10.0.0.0/24", "10.0.0.200-10.0.1.10"),
+			mustParseRanges("10.0.0.250-10.0.2.0"),
+			"[10.0.0.250-10.0.1.10 10.0.2.0-10.0.2.0]",
+		},
+	}
+	for _, test := range cases {
+		result := fmt.Sprint(IntersectMany(test.a, test.b))
+		if test.result != result {
+			t.Errorf("expecting %v, got %v when intersecting %v and %v", test.result, result, test.a, test.b)
+		}
+	}
+}
+
+func TestSubtractAll(t *testing.T) {
+	type testCase struct {
+		base     string
+		excludes []string
+		result   string
+	}
+	cases := []testCase{
+		testCase{"10.0.0.0/24", nil, "[10.0.0.0-10.0.0.255]"},
+		testCase{"10.0.0.0/24", []string{"10.0.0.0/24"}, "[]"},
+		testCase{"10.0.0.0/24", []string{"10.0.0.0/8"}, "[]"},
+		testCase{"10.0.0.0/24", []string{"10.0.0.64/26", "10.0.0.0/30", "10.0.0.200/32"},
+			"[10.0.0.4-10.0.0.63 10.0.0.128-10.0.0.199 10.0.0.201-10.0.0.255]"},
+		testCase{"10.0.0.0/24", []string{"10.0.0.0/25", "10.0.0.0/26", "10.0.0.128/26", "192.168.0.0/16"},
+			"[10.0.0.192-10.0.0.255]"},
+		testCase{"10.0.0.10-10.0.0.20", []string{"10.0.0.0/29", "10.0.0.16/28"}, "[10.0.0.10-10.0.0.15]"},
+		testCase{"255.255.255.0/24", []string{"255.255.255.128/25"}, "[255.255.255.0-255.255.255.127]"},
+		testCase{"2001:db8::/64", []string{"2001:db8::/65", "10.0.0.0/8"}, "[2001:db8:0:0:8000::-2001:db8::ffff:ffff:ffff:ffff]"},
+		testCase{"::fffe:0:0/95", []string{"0.0.0.0/1"},
+			"[::fffe:0:0-::fffe:ffff:ffff 128.0.0.0-255.255.255.255]"},
+	}
+	for _, test := range cases {
+		var excludes []*net.IPNet
+		for _, s := range test.excludes {
+			excludes = append(excludes, mustParseCIDR(s))
+		}
+		result := fmt.Sprint(SubtractAll(mustParseRanges(test.base)[0], excludes))
+		if test.result != result {
+			t.Errorf("expecting %v, got %v when subtracting %v from %v", test.result, result, test.excludes, test.base)
+		}
+	}
+}
+
+func TestFindGaps(t *testing.T) {
+	type testCase struct {
+		parent string
+		used   []string
+		result string
+	}
+	cases := []testCase{
+		testCase{"10.20.0.0/16", nil, "[10.20.0.0-10.20.255.255]"},
+		testCase{"10.20.0.0/16", []string{"10.20.1.0/24", "10.20.0.0/24", "10.20.4.0/22", "10.21.0.0/24"},
+			"[10.20.2.0-10.20.3.255 10.20.8.0-10.20.255.255]"},
+		testCase{"10.20.0.0/16", []string{"10.0.0.0/8"}, "[]"},
+		testCase{"2001:db8::/48", []string{"2001:db8:0:1::/64", "2001:db8:0:8000::/49"},
+			"[2001:db8::-2001:db8::ffff:ffff:ffff:ffff 2001:db8:0:2::-2001:db8:0:7fff:ffff:ffff:ffff:ffff]"},
+	}
+	for _, test := range cases {
+		var used []*net.IPNet
+		for _, s := range test.used {
+			used = append(used, mustParseCIDR(s))
+		}
+		result := fmt.Sprint(FindGaps(mustParseCIDR(test.parent), used))
+		if test.result != result {
+			t.Errorf("expecting %v, got %v for %v in %v", test.result, result, test.used, test.parent)
+		}
+	}
+	if gaps := FindGaps(nil, nil); gaps != nil {
+		t.Errorf("expecting nil for invalid parent, got %v", gaps)
+	}
+}
+
+func ExampleFindGaps() {
+	used := []*net.IPNet{mustParseCIDR("10.20.0.0/24"), mustParseCIDR("10.20.2.0/23")}
+	fmt.Println(FindGaps(mustParseCIDR("10.20.0.0/20"), used))
+
+	// Output:
+	// [10.20.1.0-10.20.1.255 10.20.4.0-10.20.15.255]
+}
+
+func TestParseAny(t *testing.T) {
+	type testCase struct {
+		input string
+		kind  ElementKind
+		str   string
+		rng   string
+	}
+	cases := []testCase{
+		testCase{"192.168.0.1", ElementIP, "192.168.0.1", "192.168.0.1-192.168.0.1"},
+		testCase{" 2001:db8::1 ", ElementIP, "2001:db8::1", "2001:db8::1-2001:db8::1"},
+		testCase{"192.168.0.1/30", ElementNetwork, "192.168.0.0/30", "192.168.0.0-192.168.0.3"},
+		testCase{"2001:db8::/127", ElementNetwork, "2001:db8::/127", "2001:db8::-2001:db8::1"},
+		testCase{"10.0.0.1-10.0.0.9", ElementRange, "10.0.0.1-10.0.0.9", "10.0.0.1-10.0.0.9"},
+	}
+	for _, test := range cases {
+		e, err := ParseAny(test.input)
+		if err != nil {
+			t.Errorf("unexpected error %v when parsing %q", err, test.input)
+			continue
+		}
+		if test.kind != e.Kind || test.str != e.String() || test.rng != e.Range.String() {
+			t.Errorf("expecting (%v, %v, %v), got (%v, %v, %v) when parsing %q",
+				test.kind, test.str, test.rng, e.Kind, e, e.Range, test.input)
+		}
+	}
+}
+
+func TestParseAnyFaults(t *testing.T) {
+	faultCases := []string{
+		"",
+		"foo",
+		"192.168.0.256",
+		"192.168.0.0/33",
+		"192.168.0.0/",
+		"10.0.0.9-10.0.0.1",
+	}
+	for _, test := range faultCases {
+		if e, err := ParseAny(test); err == nil {
+			t.Errorf("didn't get an error when parsing %q, got %v", test, e)
+		}
+	}
+}
+
+func ExampleParseAny() {
+	for _, s := range []string{"192.168.0.1", "192.168.0.0/24", "192.168.0.1-192.168.0.5"} {
+		e, _ := ParseAny(s)
+		fmt.Println(e.Kind, e.Range)
+	}
+
+	// Output:
+	// ip 192.168.0.1-192.168.0.1
+	// network 192.168.0.0-192.168.0.255
+	// range 192.168.0.1-192.168.0.5
+}
@@ -0,0 +1,62 @@
+// SPDX-License-Identifier: MIT-0
+
+package iputils
+
+import (
+	"fmt"
+	"net"
+)
+
+// Resolver looks up metadata of ip addresses (geolocation, ASN, owner, ...).
+// When a resolver knows nothing about the address, it returns nil tags and
+// no error.
+type Resolver interface {
+	Lookup(ip net.IP) (Tags, error)
+}
+
+// ResolverFunc is an adapter allowing to use a function as Resolver
+type ResolverFunc func(ip net.IP) (Tags, error)
+
+// Lookup calls f(ip)
+func (f ResolverFunc) Lookup(ip net.IP) (Tags, error) {
+	return f(ip)
+}
+
+// Lookup returns the tags of all prefixes containing ip, see LookupTags.
+// It makes TaggedPrefixes usable as Resolver.
+func (tp *TaggedPrefixes) Lookup(ip net.IP) (Tags, error) {
+	return tp.LookupTags(ip), nil
+}
+
+// ChainResolver consults local overrides before falling through to the
+// backends.
+type ChainResolver struct {
+	// Overrides are consulted first; if any prefix contains the address,
+	// its tags are returned and the backends are not queried.
+	Overrides *TaggedPrefixes
+
+	// Backends are queried in order, the first one returning non-nil tags
+	// wins.
+	Backends []Resolver
+}
+
+// Lookup returns the metadata of ip.  If a backend fails, the lookup stops
+// and the error is returned.  If neither the overrides nor the backends know
+// the address, nil tags are returned.
+func (r *ChainResolver) Lookup(ip net.IP) (Tags, error) {
+	if r.Overrides != nil {
+		if tags := r.Overrides.LookupTags(ip); tags != nil {
+			return tags, nil
+		}
+	}
+	for i, backend := range r.Backends {
+		tags, err := backend.Lookup(ip)
+		if err != nil {
+			return nil, fmt.Errorf("backend #%v failed to look up %v: %w", i, ip, err)
+		}
+		if tags != nil {
+			return tags, nil
+		}
+	}
+	return nil, nil
+}
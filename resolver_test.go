@@ -0,0 +1,85 @@
+// SPDX-License-Identifier: MIT-0
+
+package iputils
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestChainResolver(t *testing.T) {
+	var overrides TaggedPrefixes
+	overrides.Add(mustParseCIDR("10.0.0.0/8"), Tags{"owner": "internal"})
+
+	var geo TaggedPrefixes
+	geo.Add(mustParseCIDR("0.0.0.0/0"), Tags{"geo": "unknown"})
+	geo.Add(mustParseCIDR("10.0.0.0/8"), Tags{"geo": "eu"})
+
+	calls := 0
+	asn := ResolverFunc(func(ip net.IP) (Tags, error) {
+		calls++
+		if ip.Equal(net.ParseIP("192.0.2.1")) {
+			return Tags{"asn": "64500"}, nil
+		}
+		if ip.Equal(net.ParseIP("192.0.2.2")) {
+			return nil, errors.New("timeout")
+		}
+		return nil, nil
+	})
+
+	r := &ChainResolver{Overrides: &overrides, Backends: []Resolver{asn, &geo}}
+
+	type testCase struct {
+		ip   net.IP
+		tags Tags
+	}
+	cases := []testCase{
+		testCase{net.ParseIP("10.1.1.1"), Tags{"owner": "internal"}},
+		testCase{net.ParseIP("192.0.2.1"), Tags{"asn": "64500"}},
+		testCase{net.ParseIP("198.51.100.1"), Tags{"geo": "unknown"}},
+		testCase{net.ParseIP("2001:db8::1"), nil},
+	}
+	for _, test := range cases {
+		tags, err := r.Lookup(test.ip)
+		if err != nil {
+			t.Errorf("unexpected error %v when looking up %v", err, test.ip)
+			continue
+		}
+		if !reflect.DeepEqual(test.tags, tags) {
+			t.Errorf("expecting %v, got %v when looking up %v", test.tags, tags, test.ip)
+		}
+	}
+	if calls != 3 {
+		t.Errorf("expecting 3 backend calls, got %v", calls)
+	}
+
+	if tags, err := r.Lookup(net.ParseIP("192.0.2.2")); err == nil {
+		t.Errorf("didn't get an error from failing backend, got %v", tags)
+	}
+
+	empty := &ChainResolver{}
+	if tags, err := empty.Lookup(net.ParseIP("192.0.2.1")); tags != nil || err != nil {
+		t.Errorf("expecting (nil, nil) from empty resolver, got (%v, %v)", tags, err)
+	}
+}
+
+func ExampleChainResolver() {
+	var overrides TaggedPrefixes
+	overrides.Add(mustParseCIDR("192.0.2.0/24"), Tags{"owner": "lab"})
+
+	r := &ChainResolver{
+		Overrides: &overrides,
+		Backends: []Resolver{ResolverFunc(func(ip net.IP) (Tags, error) {
+			return Tags{"owner": "internet"}, nil
+		})},
+	}
+	fmt.Println(r.Lookup(net.ParseIP("192.0.2.10")))
+	fmt.Println(r.Lookup(net.ParseIP("198.51.100.10")))
+
+	// Output:
+	// map[owner:lab] <nil>
+	// map[owner:internet] <nil>
+}
@@ -0,0 +1,78 @@
+// SPDX-License-Identifier: MIT-0
+
+package iputils
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// ReverseZonesForPrefix returns the reverse DNS zones (in-addr.arpa for
+// IPv4, ip6.arpa for IPv6) needed to delegate the network.  Zones are cut at
+// octet boundaries for IPv4 and at nibble boundaries for IPv6, so a network
+// not aligned to a boundary needs several zones, e.g. a /22 needs four /24
+// zones.  Zone names are fully qualified, with the trailing dot.
+//
+// IPv4 networks longer than /24 can't be delegated this way; use
+// ReverseZoneRFC2317 for them.
+func ReverseZonesForPrefix(n *net.IPNet) ([]string, error) {
+	first, size, err := networkFirstAndSize(n)
+	if err != nil {
+		return nil, err
+	}
+	bits := len(first) * 8
+	ones := bits - (size.BitLen() - 1)
+	labelBits := 4
+	if len(first) == IPv4Size {
+		labelBits = 8
+		if ones > 24 {
+			return nil, fmt.Errorf("%w: network %v is longer than /24, use RFC 2317 delegation", ErrOutOfRange, n)
+		}
+	}
+	zoneLen := (ones + labelBits - 1) / labelBits * labelBits
+	step := new(big.Int).Lsh(big.NewInt(1), uint(bits-zoneLen))
+	count := 1 << uint(zoneLen-ones)
+
+	result := make([]string, 0, count)
+	value := ipToInt(first)
+	for i := 0; i < count; i++ {
+		result = append(result, reverseZoneName(intToIP(value, len(first)), zoneLen))
+		value.Add(value, step)
+	}
+	return result, nil
+}
+
+// ReverseZoneRFC2317 returns the name of the classless in-addr.arpa zone
+// (RFC 2317) for an IPv4 network from /25 to /32, e.g.
+// "0/26.2.0.192.in-addr.arpa." for 192.0.2.0/26.  The parent /24 zone is
+// expected to point to it with CNAME records.
+func ReverseZoneRFC2317(n *net.IPNet) (string, error) {
+	first, size, err := networkFirstAndSize(n)
+	if err != nil {
+		return "", err
+	}
+	ones := len(first)*8 - (size.BitLen() - 1)
+	if len(first) != IPv4Size || ones <= 24 {
+		return "", fmt.Errorf("%w: network %v is not an IPv4 network longer than /24", ErrOutOfRange, n)
+	}
+	return fmt.Sprintf("%v/%v.%v", first[3], ones, reverseZoneName(first, 24)), nil
+}
+
+// reverseZoneName returns the reverse zone name for the first zoneLen bits
+// of ip, which must be a multiple of the label size
+func reverseZoneName(ip net.IP, zoneLen int) string {
+	var labels []string
+	if len(ip) == IPv4Size {
+		for i := zoneLen/8 - 1; i >= 0; i-- {
+			labels = append(labels, strconv.Itoa(int(ip[i])))
+		}
+		return strings.Join(append(labels, "in-addr.arpa."), ".")
+	}
+	for i := zoneLen/4 - 1; i >= 0; i-- {
+		labels = append(labels, strconv.FormatUint(uint64(ip[i/2]>>(4*uint(1-i%2))&0xf), 16))
+	}
+	return strings.Join(append(labels, "ip6.arpa."), ".")
+}
@@ -0,0 +1,74 @@
+// SPDX-License-Identifier: MIT-0
+
+package iputils
+
+import (
+	"fmt"
+	"net"
+	"testing"
+)
+
+func TestReverseZonesForPrefix(t *testing.T) {
+	type testCase struct {
+		network string
+		zones   string
+	}
+	cases := []testCase{
+		testCase{"192.0.2.0/24", "[2.0.192.in-addr.arpa.]"},
+		testCase{"10.20.0.0/22", "[0.20.10.in-addr.arpa. 1.20.10.in-addr.arpa. 2.20.10.in-addr.arpa. 3.20.10.in-addr.arpa.]"},
+		testCase{"10.0.0.0/8", "[10.in-addr.arpa.]"},
+		testCase{"172.16.0.0/15", "[16.172.in-addr.arpa. 17.172.in-addr.arpa.]"},
+		testCase{"0.0.0.0/0", "[in-addr.arpa.]"},
+		testCase{"2001:db8::/32", "[8.b.d.0.1.0.0.2.ip6.arpa.]"},
+		testCase{"2001:db8:ab00::/39", "[a.a.8.b.d.0.1.0.0.2.ip6.arpa. b.a.8.b.d.0.1.0.0.2.ip6.arpa.]"},
+		testCase{"2001:db8::1/128", "[1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa.]"},
+		testCase{"::/0", "[ip6.arpa.]"},
+	}
+	for _, test := range cases {
+		zones, err := ReverseZonesForPrefix(mustParseCIDR(test.network))
+		if err != nil {
+			t.Errorf("unexpected error %v for %v", err, test.network)
+			continue
+		}
+		if fmt.Sprint(zones) != test.zones {
+			t.Errorf("expecting %v, got %v for %v", test.zones, zones, test.network)
+		}
+	}
+
+	faultCases := []*net.IPNet{
+		nil,
+		mustParseCIDR("192.0.2.0/25"),
+		&net.IPNet{IP: net.IP{10, 0, 0, 0}, Mask: net.IPMask{255, 0, 255, 0}},
+	}
+	for _, test := range faultCases {
+		if zones, err := ReverseZonesForPrefix(test); err == nil {
+			t.Errorf("didn't get an error for %v, got %v", test, zones)
+		}
+	}
+}
+
+func TestReverseZoneRFC2317(t *testing.T) {
+	type testCase struct {
+		network string
+		zone    string
+	}
+	cases := []testCase{
+		testCase{"192.0.2.0/26", "0/26.2.0.192.in-addr.arpa."},
+		testCase{"192.0.2.128/25", "128/25.2.0.192.in-addr.arpa."},
+		testCase{"192.0.2.77/32", "77/32.2.0.192.in-addr.arpa."},
+		testCase{"192.0.2.0/24", ""},
+		testCase{"2001:db8::/64", ""},
+	}
+	for _, test := range cases {
+		zone, err := ReverseZoneRFC2317(mustParseCIDR(test.network))
+		if test.zone == "" {
+			if err == nil {
+				t.Errorf("didn't get an error for %v, got %v", test.network, zone)
+			}
+			continue
+		}
+		if err != nil || zone != test.zone {
+			t.Errorf("expecting %v, got %v, %v for %v", test.zone, zone, err, test.network)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+// SPDX-License-Identifier: MIT-0
+
+package iputils
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+)
+
+// PickWeighted selects one of the networks with probability proportional to
+// its size and returns it together with a uniformly random address inside
+// it.  As a result, the address is uniformly distributed over all addresses
+// of the networks.  Overlapping networks are not merged, so their common
+// addresses are picked more often; use IPSet.Prefixes to normalize the list
+// first.  Networks which are not valid are skipped.
+//
+// The random bytes are read from random, or from crypto/rand.Reader if it is
+// nil.  Pass e.g. a seeded *math/rand.Rand to get reproducible samples.  An
+// error is returned if there are no valid networks or reading fails.
+func PickWeighted(networks []*net.IPNet, random io.Reader) (*net.IPNet, net.IP, error) {
+	type candidate struct {
+		n     *net.IPNet
+		first net.IP
+		size  *big.Int
+	}
+	var candidates []candidate
+	total := new(big.Int)
+	for _, n := range networks {
+		first, size, err := networkFirstAndSize(n)
+		if err != nil {
+			continue
+		}
+		candidates = append(candidates, candidate{n, first, size})
+		total.Add(total, size)
+	}
+	if len(candidates) == 0 {
+		return nil, nil, fmt.Errorf("%w: no valid networks to pick from", ErrInvalidNetwork)
+	}
+	if random == nil {
+		random = rand.Reader
+	}
+	offset, err := rand.Int(random, total)
+	if err != nil {
+		return nil, nil, err
+	}
+	// offset is below the total size, so it falls into one of the candidates
+	i := 0
+	for ; offset.Cmp(candidates[i].size) >= 0; i++ {
+		offset.Sub(offset, candidates[i].size)
+	}
+	c := candidates[i]
+	return c.n, intToIP(offset.Add(offset, ipToInt(c.first)), len(c.first)), nil
+}
@@ -0,0 +1,57 @@
+// SPDX-License-Identifier: MIT-0
+
+package iputils
+
+import (
+	"errors"
+	"math/rand"
+	"net"
+	"testing"
+)
+
+func TestPickWeighted(t *testing.T) {
+	networks := []*net.IPNet{
+		mustParseCIDR("10.0.0.0/24"),
+		mustParseCIDR("192.168.0.0/26"),
+		nil,
+	}
+	pick := func(seed int64) (map[string]int, string) {
+		random := rand.New(rand.NewSource(seed))
+		counts := map[string]int{}
+		picks := ""
+		for i := 0; i < 1000; i++ {
+			n, ip, err := PickWeighted(networks, random)
+			if err != nil || n == nil || !n.Contains(ip) {
+				t.Fatalf("picked address %v is not in picked network %v, error %v", ip, n, err)
+			}
+			counts[n.String()]++
+			picks += ip.String() + " "
+		}
+		return counts, picks
+	}
+	counts, picks := pick(1)
+	// the /24 is 4 times bigger than the /26, so it should get about 80% of
+	// picks; the source is seeded, so the result doesn't change between runs
+	if share := float64(counts["10.0.0.0/24"]) / 1000; share < 0.75 || share > 0.85 {
+		t.Errorf("expecting 10.0.0.0/24 to be picked about 80%% of times, got %v", counts)
+	}
+	if _, again := pick(1); again != picks {
+		t.Errorf("the same seed produced different samples")
+	}
+
+	if n, ip, err := PickWeighted([]*net.IPNet{mustParseCIDR("2001:db8::/32")}, nil); err != nil || !n.Contains(ip) {
+		t.Errorf("picked address %v is not in picked network %v, error %v", ip, n, err)
+	}
+	if n, ip, err := PickWeighted(nil, nil); !errors.Is(err, ErrInvalidNetwork) {
+		t.Errorf("expecting an error for empty list, got (%v, %v, %v)", n, ip, err)
+	}
+	if _, _, err := PickWeighted(networks, failingReader{}); err == nil {
+		t.Errorf("didn't get an error when reading random bytes failed")
+	}
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("no randomness")
+}
@@ -0,0 +1,326 @@
+// SPDX-License-Identifier: MIT-0
+
+package iputils
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+	"sort"
+)
+
+var (
+	// ipv4MappedFirst is the first IPv4-mapped IPv6 address
+	ipv4MappedFirst = net.IP(MinIPv4In6)
+
+	// ipv4MappedLast is the last IPv4-mapped IPv6 address
+	ipv4MappedLast = net.IP(MaxIPv4In6)
+
+	// ipv6BeforeV4 is the last IPv6 address before the IPv4-mapped block
+	ipv6BeforeV4 = net.IP{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0xff, 0xfe, 0xff, 0xff, 0xff, 0xff}
+
+	// ipv6AfterV4 is the first IPv6 address after the IPv4-mapped block
+	ipv6AfterV4 = net.IP{0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0}
+)
+
+// IPSet is a set of ip addresses.
+//
+// The set is stored as a sorted list of non-overlapping ranges, so large
+// networks take as much memory as single addresses.  An IPv4 address and
+// its IPv4-mapped IPv6 form are the same member of the set.
+//
+// The zero value is an empty set ready to use.
+type IPSet struct {
+	// ranges are sorted, non-overlapping and non-adjacent; every range
+	// contains addresses of one family only, IPv4 ones in the 4-byte form
+	ranges []IPRange
+}
+
+// AddIP adds a single address to the set.
+func (s *IPSet) AddIP(ip net.IP) {
+	s.AddRange(IPRange{ip, ip})
+}
+
+// AddNetwork adds all addresses of the network to the set.  Networks with
+// non-contiguous masks are ignored.
+func (s *IPSet) AddNetwork(n *net.IPNet) {
+	first, size, err := networkFirstAndSize(n)
+	if err != nil {
+		return
+	}
+	last := new(big.Int).Add(ipToInt(first), size)
+	last.Sub(last, big.NewInt(1))
+	s.AddRange(IPRange{first, intToIP(last, len(first))})
+}
+
+// AddRange adds all addresses of the range to the set.  Ranges with the
+// first address bigger than the last one are ignored.
+func (s *IPSet) AddRange(r IPRange) {
+	for _, part := range splitRangeByFamily(normalizeRange(r)) {
+		s.ranges = insertRange(s.ranges, part)
+	}
+}
+
+// Contains reports whether ip belongs to the set.
+func (s *IPSet) Contains(ip net.IP) bool {
+	if ip.To16() == nil {
+		return false
+	}
+	i := sort.Search(len(s.ranges), func(i int) bool { return Compare(s.ranges[i].Last, ip) >= 0 })
+	return i < len(s.ranges) && Compare(s.ranges[i].First, ip) <= 0
+}
+
+// NearestBelow returns the biggest member of the set which is smaller than
+// ip and true.  Only members of the same family as ip are considered.  If
+// there is no such member, false is returned.
+func (s *IPSet) NearestBelow(ip net.IP) (net.IP, bool) {
+	ip = normalizeRange(IPRange{ip, ip}).First
+	if ip == nil {
+		return nil, false
+	}
+	i := sort.Search(len(s.ranges), func(i int) bool { return Compare(s.ranges[i].Last, ip) >= 0 })
+	if i < len(s.ranges) && Compare(s.ranges[i].First, ip) < 0 {
+		result := CopyIP(ip)
+		Prev(result)
+		return result, true
+	}
+	if i > 0 && len(s.ranges[i-1].Last) != len(ip) {
+		// skip IPv4 ranges when looking for IPv6 members
+		i = sort.Search(len(s.ranges), func(i int) bool { return Compare(s.ranges[i].Last, ipv4MappedFirst) >= 0 })
+	}
+	if i == 0 || len(s.ranges[i-1].Last) != len(ip) {
+		return nil, false
+	}
+	return CopyIP(s.ranges[i-1].Last), true
+}
+
+// NearestAbove returns the smallest member of the set which is bigger than
+// ip and true.  Only members of the same family as ip are considered.  If
+// there is no such member, false is returned.
+func (s *IPSet) NearestAbove(ip net.IP) (net.IP, bool) {
+	ip = normalizeRange(IPRange{ip, ip}).First
+	if ip == nil {
+		return nil, false
+	}
+	i := sort.Search(len(s.ranges), func(i int) bool { return Compare(s.ranges[i].Last, ip) > 0 })
+	if i < len(s.ranges) && len(s.ranges[i].First) != len(ip) {
+		// skip IPv4 ranges when looking for IPv6 members
+		i = sort.Search(len(s.ranges), func(i int) bool { return Compare(s.ranges[i].Last, ipv4MappedLast) > 0 })
+	}
+	if i == len(s.ranges) || len(s.ranges[i].First) != len(ip) {
+		return nil, false
+	}
+	if Compare(s.ranges[i].First, ip) > 0 {
+		return CopyIP(s.ranges[i].First), true
+	}
+	result := CopyIP(ip)
+	Next(result)
+	return result, true
+}
+
+// Count returns the number of addresses in the set.
+func (s *IPSet) Count() *big.Int {
+	result := new(big.Int)
+	for _, r := range s.ranges {
+		result.Add(result, rangeSize(r))
+	}
+	return result
+}
+
+// CountV4 returns the number of IPv4 addresses in the set.
+func (s *IPSet) CountV4() *big.Int {
+	result := new(big.Int)
+	for _, r := range s.ranges {
+		if r.First.To4() != nil {
+			result.Add(result, rangeSize(r))
+		}
+	}
+	return result
+}
+
+// CountV6 returns the number of IPv6 addresses in the set, not including
+// IPv4-mapped ones.
+func (s *IPSet) CountV6() *big.Int {
+	result := new(big.Int)
+	for _, r := range s.ranges {
+		if r.First.To4() == nil {
+			result.Add(result, rangeSize(r))
+		}
+	}
+	return result
+}
+
+// RangeCount returns the number of ranges the set consists of.  Adjacent
+// addresses are always joined into one range, except for the last IPv6
+// address before the IPv4-mapped block and the first IPv4 address (and
+// likewise at the end of the block).
+func (s *IPSet) RangeCount() int {
+	return len(s.ranges)
+}
+
+// LargestRange returns the range of the set with the most addresses and
+// true.  If there are several such ranges, the lowest one is returned.
+// For an empty set false is returned.
+func (s *IPSet) LargestRange() (IPRange, bool) {
+	if len(s.ranges) == 0 {
+		return IPRange{}, false
+	}
+	largest, size := s.ranges[0], rangeSize(s.ranges[0])
+	for _, r := range s.ranges[1:] {
+		if rs := rangeSize(r); rs.Cmp(size) > 0 {
+			largest, size = r, rs
+		}
+	}
+	return copyRange(largest), true
+}
+
+// Ranges returns the contents of the set as a sorted list of ranges.
+// Overlapping and adjacent addresses are joined into one range, but IPv4
+// and IPv6 addresses never share a range.  IPv4 addresses are returned in
+// the 4-byte form.
+func (s *IPSet) Ranges() []IPRange {
+	result := make([]IPRange, len(s.ranges))
+	for i, r := range s.ranges {
+		result[i] = copyRange(r)
+	}
+	return result
+}
+
+// Prefixes returns the contents of the set as a sorted list of networks.
+// The list is the shortest possible one covering exactly the set.
+func (s *IPSet) Prefixes() []*net.IPNet {
+	var result []*net.IPNet
+	for _, r := range s.ranges {
+		result = append(result, rangeToNetworks(r)...)
+	}
+	return result
+}
+
+// Equal reports whether both sets contain the same addresses.
+func (s *IPSet) Equal(other *IPSet) bool {
+	if len(s.ranges) != len(other.ranges) {
+		return false
+	}
+	for i, r := range s.ranges {
+		if !r.First.Equal(other.ranges[i].First) || !r.Last.Equal(other.ranges[i].Last) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsSubsetOf reports whether every address of the set belongs to the other
+// set.  An empty set is a subset of any set.
+func (s *IPSet) IsSubsetOf(other *IPSet) bool {
+	j := 0
+	for _, r := range s.ranges {
+		// ranges of a set are maximal, so r has to fit into a single range
+		for j < len(other.ranges) && Compare(other.ranges[j].Last, r.Last) < 0 {
+			j++
+		}
+		if j == len(other.ranges) || Compare(other.ranges[j].First, r.First) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// IsProperSubsetOf reports whether the set is a subset of the other set and
+// the other set has addresses not belonging to the set.
+func (s *IPSet) IsProperSubsetOf(other *IPSet) bool {
+	return s.IsSubsetOf(other) && !s.Equal(other)
+}
+
+// V4 returns a new set with the IPv4 addresses of the set.
+func (s *IPSet) V4() *IPSet {
+	result := &IPSet{}
+	for _, r := range s.ranges {
+		if len(r.First) == IPv4Size {
+			result.ranges = append(result.ranges, copyRange(r))
+		}
+	}
+	return result
+}
+
+// V6 returns a new set with the IPv6 addresses of the set, not including
+// IPv4-mapped ones.
+func (s *IPSet) V6() *IPSet {
+	result := &IPSet{}
+	for _, r := range s.ranges {
+		if len(r.First) == IPv6Size {
+			result.ranges = append(result.ranges, copyRange(r))
+		}
+	}
+	return result
+}
+
+func (s *IPSet) clone() *IPSet {
+	return &IPSet{ranges: s.Ranges()}
+}
+
+func (s *IPSet) String() string {
+	return fmt.Sprint(s.ranges)
+}
+
+// insertRange adds the range of one family to the normalized list of ranges
+func insertRange(ranges []IPRange, r IPRange) []IPRange {
+	if r.First == nil {
+		return ranges
+	}
+	// i is the first range which overlaps with r, touches it or follows it
+	i := sort.Search(len(ranges), func(i int) bool {
+		return Compare(ranges[i].Last, r.First) >= 0 || joinable(ranges[i], r)
+	})
+	j := i
+	for ; j < len(ranges) && joinable(r, ranges[j]); j++ {
+		if Compare(ranges[j].First, r.First) < 0 {
+			r.First = ranges[j].First
+		}
+		if Compare(ranges[j].Last, r.Last) > 0 {
+			r.Last = ranges[j].Last
+		}
+	}
+	if i == j {
+		ranges = append(ranges, IPRange{})
+		copy(ranges[i+1:], ranges[i:])
+		ranges[i] = r
+		return ranges
+	}
+	ranges[i] = r
+	return append(ranges[:i+1], ranges[j:]...)
+}
+
+// splitRangeByFamily splits the normalized range into parts containing addresses
+// of one family only.
+func splitRangeByFamily(r IPRange) []IPRange {
+	if r.First == nil {
+		return nil
+	}
+	firstV4, lastV4 := r.First.To4() != nil, r.Last.To4() != nil
+	switch {
+	case firstV4 && lastV4:
+		return []IPRange{{r.First.To4(), r.Last.To4()}}
+	case firstV4:
+		return []IPRange{{r.First.To4(), CopyIP(MaxIPv4)}, {CopyIP(ipv6AfterV4), r.Last}}
+	case Compare(r.Last, ipv4MappedFirst) < 0 || Compare(r.First, ipv4MappedLast) > 0:
+		return []IPRange{r}
+	case lastV4:
+		return []IPRange{{r.First, CopyIP(ipv6BeforeV4)}, {CopyIP(MinIPv4), r.Last.To4()}}
+	}
+	return []IPRange{
+		{r.First, CopyIP(ipv6BeforeV4)},
+		{CopyIP(MinIPv4), CopyIP(MaxIPv4)},
+		{CopyIP(ipv6AfterV4), r.Last},
+	}
+}
+
+// rangeSize returns the number of addresses in the normalized range
+func rangeSize(r IPRange) *big.Int {
+	size := ipToInt(r.Last)
+	size.Sub(size, ipToInt(r.First))
+	return size.Add(size, big.NewInt(1))
+}
+
+func copyRange(r IPRange) IPRange {
+	return IPRange{CopyIP(r.First), CopyIP(r.Last)}
+}
@@ -0,0 +1,296 @@
+// SPDX-License-Identifier: MIT-0
+
+package iputils
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+	"testing"
+)
+
+// mustParseSet builds a set of ParseAny specs and panics on failure
+func mustParseSet(specs ...string) *IPSet {
+	var s IPSet
+	for _, r := range mustParseRanges(specs...) {
+		s.AddRange(r)
+	}
+	return &s
+}
+
+func TestIPSetAdd(t *testing.T) {
+	type testCase struct {
+		specs  []string
+		result string
+	}
+	cases := []testCase{
+		testCase{nil, "[]"},
+		testCase{[]string{"10.0.0.1", "10.0.0.3", "10.0.0.2"}, "[10.0.0.1-10.0.0.3]"},
+		testCase{[]string{"10.0.0.5", "10.0.0.1", "10.0.0.3"}, "[10.0.0.1-10.0.0.1 10.0.0.3-10.0.0.3 10.0.0.5-10.0.0.5]"},
+		testCase{[]string{"10.0.0.0/24", "10.0.0.100-10.0.1.5", "10.0.1.6"}, "[10.0.0.0-10.0.1.6]"},
+		testCase{[]string{"10.0.0.1", "10.0.0.3", "10.0.0.5", "10.0.0.2-10.0.0.4"}, "[10.0.0.1-10.0.0.5]"},
+		testCase{[]string{"10.0.0.1", "10.0.0.8", "10.0.0.0/29"}, "[10.0.0.0-10.0.0.8]"},
+		testCase{[]string{"10.0.0.1", "10.0.0.9", "10.0.0.0/29"}, "[10.0.0.0-10.0.0.7 10.0.0.9-10.0.0.9]"},
+		testCase{[]string{"::ffff:10.0.0.1", "10.0.0.2"}, "[10.0.0.1-10.0.0.2]"},
+		testCase{[]string{"::/0"},
+			"[::-::fffe:ffff:ffff 0.0.0.0-255.255.255.255 ::1:0:0:0-ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff]"},
+		testCase{[]string{"2001:db8::/64", "2001:db8:0:1::/64", "10.0.0.0/8"},
+			"[10.0.0.0-10.255.255.255 2001:db8::-2001:db8:0:1:ffff:ffff:ffff:ffff]"},
+	}
+	for _, test := range cases {
+		result := mustParseSet(test.specs...).String()
+		if test.result != result {
+			t.Errorf("expecting %v, got %v for %v", test.result, result, test.specs)
+		}
+	}
+}
+
+func TestIPSetAddMixedFamilyRange(t *testing.T) {
+	type testCase struct {
+		r      IPRange
+		result string
+	}
+	cases := []testCase{
+		testCase{IPRange{net.ParseIP("::fffe:ffff:ffff"), net.ParseIP("0.0.0.5")},
+			"[::fffe:ffff:ffff-::fffe:ffff:ffff 0.0.0.0-0.0.0.5]"},
+		testCase{IPRange{net.ParseIP("255.255.255.250"), net.ParseIP("::1:0:0:1")},
+			"[255.255.255.250-255.255.255.255 ::1:0:0:0-::1:0:0:1]"},
+		testCase{IPRange{net.ParseIP("::1"), net.ParseIP("::2")}, "[::1-::2]"},
+		testCase{IPRange{net.ParseIP("::2"), net.ParseIP("::1")}, "[]"},
+		testCase{IPRange{nil, net.ParseIP("::1")}, "[]"},
+	}
+	for _, test := range cases {
+		var s IPSet
+		s.AddRange(test.r)
+		if result := s.String(); test.result != result {
+			t.Errorf("expecting %v, got %v for %v", test.result, result, test.r)
+		}
+	}
+}
+
+func TestIPSetContains(t *testing.T) {
+	s := mustParseSet("10.0.0.0/24", "192.168.0.1", "2001:db8::/64")
+	var n IPSet
+	n.AddNetwork(&net.IPNet{IP: net.ParseIP("172.16.0.1"), Mask: net.CIDRMask(30, 32)})
+	type testCase struct {
+		set    *IPSet
+		ip     net.IP
+		result bool
+	}
+	cases := []testCase{
+		testCase{s, net.ParseIP("10.0.0.0"), true},
+		testCase{s, net.IP{10, 0, 0, 255}, true},
+		testCase{s, net.ParseIP("10.0.1.0"), false},
+		testCase{s, net.ParseIP("192.168.0.1"), true},
+		testCase{s, net.ParseIP("192.168.0.2"), false},
+		testCase{s, net.ParseIP("2001:db8::ffff"), true},
+		testCase{s, net.ParseIP("2001:db9::"), false},
+		testCase{s, nil, false},
+		testCase{&n, net.ParseIP("172.16.0.3"), true},
+		testCase{&n, net.ParseIP("172.16.0.4"), false},
+		testCase{&IPSet{}, net.ParseIP("10.0.0.0"), false},
+	}
+	for _, test := range cases {
+		if result := test.set.Contains(test.ip); test.result != result {
+			t.Errorf("expecting %v, got %v for %v in %v", test.result, result, test.ip, test.set)
+		}
+	}
+}
+
+func TestIPSetCount(t *testing.T) {
+	type testCase struct {
+		specs   []string
+		count   string
+		countV4 string
+		countV6 string
+		ranges  int
+		largest string
+	}
+	cases := []testCase{
+		testCase{nil, "0", "0", "0", 0, ""},
+		testCase{[]string{"10.0.0.0/24", "10.0.2.0/23", "192.168.0.1"}, "769", "769", "0", 3, "10.0.2.0-10.0.3.255"},
+		testCase{[]string{"10.0.0.0/31", "2001:db8::/64"}, "18446744073709551618", "2", "18446744073709551616", 2,
+			"2001:db8::-2001:db8::ffff:ffff:ffff:ffff"},
+		testCase{[]string{"::/0"}, "340282366920938463463374607431768211456", "4294967296",
+			"340282366920938463463374607427473244160", 3, "::1:0:0:0-ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff"},
+	}
+	for _, test := range cases {
+		s := mustParseSet(test.specs...)
+		if count := s.Count().String(); test.count != count {
+			t.Errorf("expecting count %v, got %v for %v", test.count, count, s)
+		}
+		if count := s.CountV4().String(); test.countV4 != count {
+			t.Errorf("expecting IPv4 count %v, got %v for %v", test.countV4, count, s)
+		}
+		if count := s.CountV6().String(); test.countV6 != count {
+			t.Errorf("expecting IPv6 count %v, got %v for %v", test.countV6, count, s)
+		}
+		if ranges := s.RangeCount(); test.ranges != ranges {
+			t.Errorf("expecting %v ranges, got %v for %v", test.ranges, ranges, s)
+		}
+		largest, ok := s.LargestRange()
+		if ok != (test.largest != "") || (ok && test.largest != largest.String()) {
+			t.Errorf("expecting largest range %q, got (%v, %v) for %v", test.largest, largest, ok, s)
+		}
+	}
+	if total := new(big.Int).Lsh(big.NewInt(1), 128); mustParseSet("::/0").Count().Cmp(total) != 0 {
+		t.Errorf("expecting the whole address space to contain 2^128 addresses")
+	}
+}
+
+func TestIPSetRangesAndPrefixes(t *testing.T) {
+	type testCase struct {
+		specs    []string
+		ranges   string
+		prefixes string
+	}
+	cases := []testCase{
+		testCase{nil, "[]", "[]"},
+		testCase{[]string{"10.0.0.0/24", "10.0.1.0/24"}, "[10.0.0.0-10.0.1.255]", "[10.0.0.0/23]"},
+		testCase{[]string{"10.0.0.1-10.0.0.10"}, "[10.0.0.1-10.0.0.10]",
+			"[10.0.0.1/32 10.0.0.2/31 10.0.0.4/30 10.0.0.8/31 10.0.0.10/32]"},
+		testCase{[]string{"0.0.0.0-255.255.255.255"}, "[0.0.0.0-255.255.255.255]", "[0.0.0.0/0]"},
+		testCase{[]string{"255.255.255.255", "0.0.0.0"}, "[0.0.0.0-0.0.0.0 255.255.255.255-255.255.255.255]",
+			"[0.0.0.0/32 255.255.255.255/32]"},
+		testCase{[]string{"2001:db8::/64", "10.0.0.0/8", "2001:db8:0:1::-2001:db8:0:1::1"},
+			"[10.0.0.0-10.255.255.255 2001:db8::-2001:db8:0:1::1]",
+			"[10.0.0.0/8 2001:db8::/64 2001:db8:0:1::/127]"},
+		testCase{[]string{"::fffe:0:0/95"}, "[::fffe:0:0-::fffe:ffff:ffff 0.0.0.0-255.255.255.255]",
+			"[::fffe:0:0/96 0.0.0.0/0]"},
+	}
+	for _, test := range cases {
+		s := mustParseSet(test.specs...)
+		if ranges := fmt.Sprint(s.Ranges()); test.ranges != ranges {
+			t.Errorf("expecting ranges %v, got %v for %v", test.ranges, ranges, test.specs)
+		}
+		if prefixes := fmt.Sprint(s.Prefixes()); test.prefixes != prefixes {
+			t.Errorf("expecting prefixes %v, got %v for %v", test.prefixes, prefixes, test.specs)
+		}
+	}
+}
+
+func TestIPSetNearest(t *testing.T) {
+	s := mustParseSet("10.0.0.0/24", "10.0.2.10-10.0.2.20", "2001:db8::/64")
+	type testCase struct {
+		ip    net.IP
+		below string
+		above string
+	}
+	cases := []testCase{
+		testCase{net.ParseIP("10.0.1.50"), "10.0.0.255", "10.0.2.10"},
+		testCase{net.IP{10, 0, 0, 50}, "10.0.0.49", "10.0.0.51"},
+		testCase{net.ParseIP("10.0.0.0"), "", "10.0.0.1"},
+		testCase{net.ParseIP("10.0.2.20"), "10.0.2.19", ""},
+		testCase{net.ParseIP("10.0.2.10"), "10.0.0.255", "10.0.2.11"},
+		testCase{net.ParseIP("9.255.255.255"), "", "10.0.0.0"},
+		testCase{net.ParseIP("192.168.0.1"), "10.0.2.20", ""},
+		testCase{net.ParseIP("::1"), "", "2001:db8::"},
+		testCase{net.ParseIP("2001:db8:1::"), "2001:db8::ffff:ffff:ffff:ffff", ""},
+		testCase{net.ParseIP("2001:db8:0:1::"), "2001:db8::ffff:ffff:ffff:ffff", ""},
+		testCase{net.ParseIP("ffff::"), "2001:db8::ffff:ffff:ffff:ffff", ""},
+		testCase{nil, "", ""},
+	}
+	for _, test := range cases {
+		below, ok := s.NearestBelow(test.ip)
+		if ok != (test.below != "") || (ok && test.below != below.String()) {
+			t.Errorf("expecting nearest below %q, got (%v, %v) for %v", test.below, below, ok, test.ip)
+		}
+		above, ok := s.NearestAbove(test.ip)
+		if ok != (test.above != "") || (ok && test.above != above.String()) {
+			t.Errorf("expecting nearest above %q, got (%v, %v) for %v", test.above, above, ok, test.ip)
+		}
+	}
+}
+
+func TestIPSetNearestSkipsIPv4(t *testing.T) {
+	s := mustParseSet("::5", "10.0.0.0/8", "192.168.0.0/16", "2001:db8::1")
+	if below, ok := s.NearestBelow(net.ParseIP("2001:db8::")); !ok || !below.Equal(net.ParseIP("::5")) {
+		t.Errorf("expecting ::5 below 2001:db8::, got (%v, %v)", below, ok)
+	}
+	if above, ok := s.NearestAbove(net.ParseIP("::6")); !ok || !above.Equal(net.ParseIP("2001:db8::1")) {
+		t.Errorf("expecting 2001:db8::1 above ::6, got (%v, %v)", above, ok)
+	}
+	if below, ok := s.NearestBelow(net.ParseIP("10.0.0.0")); ok {
+		t.Errorf("expecting no IPv4 member below 10.0.0.0, got %v", below)
+	}
+}
+
+func TestIPSetSubsets(t *testing.T) {
+	type testCase struct {
+		a        *IPSet
+		b        *IPSet
+		equal    bool
+		subset   bool
+		superset bool
+	}
+	cases := []testCase{
+		testCase{mustParseSet(), mustParseSet(), true, true, true},
+		testCase{mustParseSet(), mustParseSet("10.0.0.1"), false, true, false},
+		testCase{mustParseSet("10.0.0.0/24"), mustParseSet("10.0.0.0-10.0.0.127", "10.0.0.128/25"), true, true, true},
+		testCase{mustParseSet("10.0.0.0/25"), mustParseSet("10.0.0.0/24"), false, true, false},
+		testCase{mustParseSet("10.0.0.0/25", "10.0.1.1"), mustParseSet("10.0.0.0/24"), false, false, false},
+		testCase{mustParseSet("10.0.0.5", "10.0.2.0/24"), mustParseSet("10.0.0.0/24", "10.0.1.0/24", "10.0.2.0/23"), false, true, false},
+		testCase{mustParseSet("10.0.0.0/24"), mustParseSet("::ffff:10.0.0.0/120"), true, true, true},
+		testCase{mustParseSet("10.0.0.0/24"), mustParseSet("::/0"), false, true, false},
+		testCase{mustParseSet("::/0"), mustParseSet("::/1", "8000::/1"), true, true, true},
+		testCase{mustParseSet("2001:db8::/32"), mustParseSet("2001:db8::/33"), false, false, true},
+		testCase{mustParseSet("10.0.0.0/24"), mustParseSet("10.0.0.0/24", "2001:db8::/32"), false, true, false},
+	}
+	for _, test := range cases {
+		if equal := test.a.Equal(test.b); equal != test.equal {
+			t.Errorf("expecting Equal %v, got %v for %v and %v", test.equal, equal, test.a, test.b)
+		}
+		if subset := test.a.IsSubsetOf(test.b); subset != test.subset {
+			t.Errorf("expecting IsSubsetOf %v, got %v for %v and %v", test.subset, subset, test.a, test.b)
+		}
+		if superset := test.b.IsSubsetOf(test.a); superset != test.superset {
+			t.Errorf("expecting reverse IsSubsetOf %v, got %v for %v and %v", test.superset, superset, test.a, test.b)
+		}
+		if proper := test.a.IsProperSubsetOf(test.b); proper != (test.subset && !test.equal) {
+			t.Errorf("unexpected IsProperSubsetOf %v for %v and %v", proper, test.a, test.b)
+		}
+	}
+}
+
+func TestIPSetFamilies(t *testing.T) {
+	s := mustParseSet("::5", "10.0.0.0/8", "192.168.0.0/16", "2001:db8::/32")
+	if v4 := s.V4(); v4.String() != "[10.0.0.0-10.255.255.255 192.168.0.0-192.168.255.255]" {
+		t.Errorf("unexpected IPv4 part %v", v4)
+	}
+	if v6 := s.V6(); v6.String() != "[::5-::5 2001:db8::-2001:db8:ffff:ffff:ffff:ffff:ffff:ffff]" {
+		t.Errorf("unexpected IPv6 part %v", v6)
+	}
+	v4 := s.V4()
+	v4.AddIP(net.ParseIP("1.1.1.1"))
+	if s.Contains(net.ParseIP("1.1.1.1")) {
+		t.Errorf("changing the IPv4 part changed the set")
+	}
+	if empty := new(IPSet).V6(); empty.RangeCount() != 0 {
+		t.Errorf("expecting an empty set, got %v", empty)
+	}
+}
+
+func ExampleIPSet_Prefixes() {
+	var s IPSet
+	s.AddRange(IPRange{net.ParseIP("192.168.0.0"), net.ParseIP("192.168.1.127")})
+	s.AddIP(net.ParseIP("10.0.0.1"))
+
+	fmt.Println(s.Ranges())
+	fmt.Println(s.Prefixes())
+
+	// Output:
+	// [10.0.0.1-10.0.0.1 192.168.0.0-192.168.1.127]
+	// [10.0.0.1/32 192.168.0.0/24 192.168.1.0/25]
+}
+
+func ExampleIPSet_Count() {
+	var s IPSet
+	s.AddNetwork(mustParseCIDR("192.168.0.0/24"))
+	s.AddRange(IPRange{net.ParseIP("192.168.1.0"), net.ParseIP("192.168.1.9")})
+	s.AddIP(net.ParseIP("2001:db8::1"))
+
+	fmt.Println(s.Count(), s.CountV4(), s.CountV6(), s.RangeCount())
+
+	// Output:
+	// 267 266 1 2
+}
@@ -0,0 +1,62 @@
+// SPDX-License-Identifier: MIT-0
+
+package iputils
+
+import (
+	"fmt"
+	"net"
+)
+
+// StreamingSummarizer aggregates a stream of addresses sorted in ascending
+// order into networks without keeping the addresses.  Only the current run
+// of consecutive addresses is remembered, so the memory use doesn't depend
+// on the length of the stream.
+//
+// The zero value is ready to use.
+type StreamingSummarizer struct {
+	// run is the current run of consecutive addresses, First is nil if
+	// there is none
+	run IPRange
+}
+
+// Add consumes the next address.  When the address doesn't continue the
+// current run of consecutive addresses, the run is finished and returned as
+// the shortest list of networks covering it.  Repeated addresses are
+// ignored.  An error is returned for invalid addresses and for addresses
+// smaller than the previous one (see Compare).
+func (s *StreamingSummarizer) Add(ip net.IP) ([]*net.IPNet, error) {
+	normalized := normalizeRange(IPRange{ip, ip}).First
+	if normalized == nil {
+		return nil, fmt.Errorf("%w %v", ErrInvalidIP, ip)
+	}
+	ip = normalized
+	if s.run.First == nil {
+		s.run = IPRange{CopyIP(ip), CopyIP(ip)}
+		return nil, nil
+	}
+	switch c := Compare(ip, s.run.Last); {
+	case c < 0:
+		return nil, fmt.Errorf("%w: address %v follows %v", ErrOutOfRange, ip, s.run.Last)
+	case c == 0:
+		return nil, nil
+	}
+	next := CopyIP(s.run.Last)
+	if len(ip) == len(next) && Next(next) && next.Equal(ip) {
+		s.run.Last = next
+		return nil, nil
+	}
+	result := rangeToNetworks(s.run)
+	s.run = IPRange{CopyIP(ip), CopyIP(ip)}
+	return result, nil
+}
+
+// Flush finishes the current run and returns it as networks.  The
+// summarizer can be used for a new stream afterwards.
+func (s *StreamingSummarizer) Flush() []*net.IPNet {
+	if s.run.First == nil {
+		return nil
+	}
+	result := rangeToNetworks(s.run)
+	s.run = IPRange{}
+	return result
+}
@@ -0,0 +1,65 @@
+// SPDX-License-Identifier: MIT-0
+
+package iputils
+
+import (
+	"fmt"
+	"net"
+	"testing"
+)
+
+func TestStreamingSummarizer(t *testing.T) {
+	type testCase struct {
+		ips      []string
+		networks string
+	}
+	cases := []testCase{
+		testCase{[]string{"10.0.0.0", "10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.5"}, "[10.0.0.0/30 10.0.0.5/32]"},
+		testCase{[]string{"10.0.0.1", "10.0.0.2", "10.0.0.2", "10.0.0.3", "10.0.0.4"}, "[10.0.0.1/32 10.0.0.2/31 10.0.0.4/32]"},
+		testCase{[]string{"255.255.255.254", "::ffff:255.255.255.255"}, "[255.255.255.254/31]"},
+		testCase{[]string{"::fffe:ffff:ffff", "0.0.0.0", "0.0.0.1"}, "[::fffe:ffff:ffff/128 0.0.0.0/31]"},
+		testCase{[]string{"2001:db8::", "2001:db8::1", "2001:db8::4"}, "[2001:db8::/127 2001:db8::4/128]"},
+		testCase{[]string{}, "[]"},
+	}
+	for _, test := range cases {
+		var s StreamingSummarizer
+		networks := []*net.IPNet{}
+		for _, ip := range test.ips {
+			emitted, err := s.Add(net.ParseIP(ip))
+			if err != nil {
+				t.Errorf("unexpected error %v for %v", err, ip)
+			}
+			networks = append(networks, emitted...)
+		}
+		networks = append(networks, s.Flush()...)
+		if fmt.Sprint(networks) != test.networks {
+			t.Errorf("expecting %v, got %v for %v", test.networks, networks, test.ips)
+		}
+	}
+}
+
+func TestStreamingSummarizerIncremental(t *testing.T) {
+	var s StreamingSummarizer
+	iter := GetIPRangeIterator(net.ParseIP("192.168.0.0"), net.ParseIP("192.168.3.255"))
+	for ip, ok := iter.Next(); ok; ip, ok = iter.Next() {
+		if emitted, err := s.Add(ip); err != nil || emitted != nil {
+			t.Fatalf("unexpected result %v, %v for %v", emitted, err, ip)
+		}
+	}
+	emitted, err := s.Add(net.ParseIP("192.168.5.1"))
+	if err != nil || fmt.Sprint(emitted) != "[192.168.0.0/22]" {
+		t.Errorf("unexpected result %v, %v", emitted, err)
+	}
+	if _, err := s.Add(net.ParseIP("192.168.5.0")); err == nil {
+		t.Errorf("didn't get an error for a descending address")
+	}
+	if _, err := s.Add(net.IP{1, 2, 3}); err == nil {
+		t.Errorf("didn't get an error for an invalid address")
+	}
+	if networks := s.Flush(); fmt.Sprint(networks) != "[192.168.5.1/32]" {
+		t.Errorf("unexpected flushed networks %v", networks)
+	}
+	if networks := s.Flush(); networks != nil {
+		t.Errorf("expecting nothing after flush, got %v", networks)
+	}
+}
@@ -0,0 +1,236 @@
+// SPDX-License-Identifier: MIT-0
+
+package iputils
+
+import (
+	"fmt"
+	"net"
+)
+
+// Tags contains string attributes (owner, environment, ASN, ...) attached
+// to a network prefix.
+type Tags map[string]string
+
+// InsertPolicy defines how TaggedPrefixes.Add handles a network overlapping
+// with networks already in the store.
+type InsertPolicy int
+
+const (
+	// InsertMerge merges the tags of the same network, overlapping networks
+	// are stored side by side (see TaggedPrefixes.MergeFunc for merging
+	// them).  This is the default policy.
+	InsertMerge InsertPolicy = iota
+
+	// InsertReject refuses to add a network which is already in the store
+	// or overlaps with any stored network.
+	InsertReject
+
+	// InsertReplace replaces the tags of the same network and removes the
+	// stored networks contained in the new one.  Stored supernets are kept.
+	InsertReplace
+
+	// InsertSplit keeps the stored networks disjoint, the most specific
+	// network wins: a stored supernet is split into the parts not covered by
+	// the new network, and the new network is stored only in the parts not
+	// covered by stored subnets.  The store stays disjoint only if all
+	// networks were added with this policy.
+	InsertSplit
+)
+
+func (p InsertPolicy) String() string {
+	switch p {
+	case InsertMerge:
+		return "merge"
+	case InsertReject:
+		return "reject"
+	case InsertReplace:
+		return "replace"
+	case InsertSplit:
+		return "split"
+	}
+	return fmt.Sprintf("InsertPolicy(%d)", int(p))
+}
+
+// TaggedPrefixes is a store of network prefixes labeled with tags.
+//
+// IPv4 and IPv6 prefixes are kept apart: IPv4 addresses, including
+// IPv4-mapped ones, are matched against IPv4 prefixes only.
+//
+// The zero value is an empty store ready to use.
+type TaggedPrefixes struct {
+	// Policy is applied when a network overlapping with the stored ones is
+	// added.
+	Policy InsertPolicy
+
+	// MergeFunc, if set, combines tags of overlapping networks under the
+	// InsertMerge policy.  When a network is added, it is called once for
+	// the network itself with the tags it already has merged over the tags
+	// of its stored supernets (as LookupTags merges them), and once for
+	// every stored subnet with the tags of that subnet.  It receives copies
+	// of the tags and returns the tags to store for the network n.  Without
+	// it, the new values win for the same network and overlapping networks
+	// keep their own tags.
+	MergeFunc func(n *net.IPNet, existing, added Tags) Tags
+
+	trie  prefixTrie
+	count int
+}
+
+// Add attaches tags to the network according to the insertion policy.
+func (tp *TaggedPrefixes) Add(n *net.IPNet, tags Tags) error {
+	if _, _, err := networkKey(n); err != nil {
+		return err
+	}
+	switch tp.Policy {
+	case InsertMerge:
+		return tp.merge(n, tags)
+	case InsertReject:
+		if tp.trie.get(n) != nil || len(tp.trie.supernets(n)) > 0 || len(tp.trie.subnets(n)) > 0 {
+			return fmt.Errorf("%w: %v overlaps with stored networks", ErrConflict, n)
+		}
+		return tp.set(n, tags)
+	case InsertReplace:
+		for _, node := range tp.trie.subnets(n) {
+			tp.Remove(node.network)
+		}
+		return tp.set(n, tags)
+	case InsertSplit:
+		return tp.split(n, tags)
+	}
+	return fmt.Errorf("%w: unknown insert policy %v", ErrOutOfRange, tp.Policy)
+}
+
+// merge merges the tags into the tags of the network, new values win
+func (tp *TaggedPrefixes) merge(n *net.IPNet, tags Tags) error {
+	if tp.MergeFunc != nil {
+		return tp.mergeFunc(n, tags)
+	}
+	node, err := tp.trie.insert(n)
+	if err != nil {
+		return err
+	}
+	existing, _ := node.value.(Tags)
+	if existing == nil {
+		existing = Tags{}
+		node.value = existing
+		tp.count++
+	}
+	for k, v := range tags {
+		existing[k] = v
+	}
+	return nil
+}
+
+// mergeFunc merges the tags into the network and its stored subnets with
+// MergeFunc
+func (tp *TaggedPrefixes) mergeFunc(n *net.IPNet, tags Tags) error {
+	existing := Tags{}
+	for _, node := range tp.trie.supernets(n) {
+		for k, v := range node.value.(Tags) {
+			existing[k] = v
+		}
+	}
+	if node := tp.trie.get(n); node != nil {
+		for k, v := range node.value.(Tags) {
+			existing[k] = v
+		}
+	}
+	for _, node := range tp.trie.subnets(n) {
+		node.value = copyTags(tp.MergeFunc(canonicalNetwork(node.network), copyTags(node.value.(Tags)), copyTags(tags)))
+	}
+	return tp.set(n, tp.MergeFunc(canonicalNetwork(n), existing, copyTags(tags)))
+}
+
+// set replaces the tags of the network
+func (tp *TaggedPrefixes) set(n *net.IPNet, tags Tags) error {
+	node, err := tp.trie.insert(n)
+	if err != nil {
+		return err
+	}
+	if node.value == nil {
+		tp.count++
+	}
+	node.value = copyTags(tags)
+	return nil
+}
+
+// split adds the network keeping the stored networks disjoint
+func (tp *TaggedPrefixes) split(n *net.IPNet, tags Tags) error {
+	for _, node := range tp.trie.supernets(n) {
+		supernet, supernetTags := node.network, node.value.(Tags)
+		ones, _ := supernet.Mask.Size()
+		tp.Remove(supernet)
+		for _, part := range splitOff(n, ones) {
+			tp.set(part, supernetTags)
+		}
+	}
+	if len(tp.trie.subnets(n)) == 0 {
+		return tp.set(n, tags)
+	}
+	parts := tp.trie.uncovered(n)
+	tp.Remove(n)
+	for _, part := range parts {
+		tp.set(part, tags)
+	}
+	return nil
+}
+
+// Remove removes the network and its tags from the store.  It returns false
+// if the network was not in the store.
+func (tp *TaggedPrefixes) Remove(n *net.IPNet) bool {
+	if !tp.trie.remove(n) {
+		return false
+	}
+	tp.count--
+	return true
+}
+
+// Tags returns a copy of the tags attached exactly to the network or nil if
+// the network is not in the store.
+func (tp *TaggedPrefixes) Tags(n *net.IPNet) Tags {
+	node := tp.trie.get(n)
+	if node == nil {
+		return nil
+	}
+	return copyTags(node.value.(Tags))
+}
+
+// Len returns the number of prefixes in the store.
+func (tp *TaggedPrefixes) Len() int {
+	return tp.count
+}
+
+// LookupTags returns the tags of all prefixes containing ip merged together.
+// Prefixes are applied from the least specific to the most specific one, so
+// the values of a more specific prefix override the values of its
+// supernets.  If no prefix contains ip, nil is returned.
+func (tp *TaggedPrefixes) LookupTags(ip net.IP) Tags {
+	var result Tags
+	for _, node := range tp.trie.matches(ip) {
+		if result == nil {
+			result = Tags{}
+		}
+		for k, v := range node.value.(Tags) {
+			result[k] = v
+		}
+	}
+	return result
+}
+
+// Networks returns all prefixes in the store in ascending order.
+func (tp *TaggedPrefixes) Networks() []*net.IPNet {
+	var result []*net.IPNet
+	tp.trie.walk(func(node *trieNode) bool {
+		result = append(result, canonicalNetwork(node.network))
+		return true
+	})
+	return result
+}
+
+func copyTags(tags Tags) Tags {
+	result := make(Tags, len(tags))
+	for k, v := range tags {
+		result[k] = v
+	}
+	return result
+}
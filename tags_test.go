@@ -0,0 +1,258 @@
+// SPDX-License-Identifier: MIT-0
+
+package iputils
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestTaggedPrefixesLookupTags(t *testing.T) {
+	var tp TaggedPrefixes
+	tp.Add(mustParseCIDR("10.0.0.0/8"), Tags{"owner": "infra", "env": "prod"})
+	tp.Add(mustParseCIDR("10.20.0.0/16"), Tags{"env": "staging", "asn": "64512"})
+	tp.Add(mustParseCIDR("10.20.30.0/24"), Tags{"owner": "team-a"})
+	tp.Add(mustParseCIDR("2001:db8::/32"), Tags{"geo": "eu"})
+	tp.Add(mustParseCIDR("::/0"), Tags{"default": "v6"})
+	tp.Add(mustParseCIDR("0.0.0.0/0"), Tags{"default": "v4"})
+
+	type testCase struct {
+		ip   net.IP
+		tags Tags
+	}
+	cases := []testCase{
+		testCase{net.ParseIP("10.1.2.3"), Tags{"default": "v4", "owner": "infra", "env": "prod"}},
+		testCase{net.ParseIP("10.20.1.1"), Tags{"default": "v4", "owner": "infra", "env": "staging", "asn": "64512"}},
+		testCase{[]byte{10, 20, 30, 40}, Tags{"default": "v4", "owner": "team-a", "env": "staging", "asn": "64512"}},
+		testCase{net.ParseIP("192.168.0.1"), Tags{"default": "v4"}},
+		testCase{net.ParseIP("2001:db8::1"), Tags{"default": "v6", "geo": "eu"}},
+		// IPv6 prefixes don't contain IPv4 addresses
+		testCase{net.ParseIP("::ffff:192.168.0.1"), Tags{"default": "v4"}},
+		testCase{net.ParseIP("::1"), Tags{"default": "v6"}},
+		testCase{nil, nil},
+	}
+	for _, test := range cases {
+		tags := tp.LookupTags(test.ip)
+		if !reflect.DeepEqual(test.tags, tags) {
+			t.Errorf("expecting %v, got %v for %v", test.tags, tags, test.ip)
+		}
+	}
+}
+
+func TestTaggedPrefixesAddRemove(t *testing.T) {
+	var tp TaggedPrefixes
+	if err := tp.Add(mustParseCIDR("192.168.1.0/24"), Tags{"a": "1"}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	// host bits are ignored, tags are merged into the existing prefix
+	network := &net.IPNet{IP: net.ParseIP("192.168.1.77"), Mask: net.CIDRMask(24, 32)}
+	if err := tp.Add(network, Tags{"a": "2", "b": "3"}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if tp.Len() != 1 {
+		t.Errorf("expecting 1 prefix, got %v", tp.Len())
+	}
+	if tags := tp.Tags(mustParseCIDR("192.168.1.0/24")); !reflect.DeepEqual(Tags{"a": "2", "b": "3"}, tags) {
+		t.Errorf("unexpected tags %v", tags)
+	}
+	if tags := tp.Tags(mustParseCIDR("192.168.0.0/16")); tags != nil {
+		t.Errorf("expecting no tags, got %v", tags)
+	}
+	if !tp.Remove(mustParseCIDR("192.168.1.0/24")) {
+		t.Errorf("failed to remove existing prefix")
+	}
+	if tp.Remove(mustParseCIDR("192.168.1.0/24")) {
+		t.Errorf("removed a prefix twice")
+	}
+	if tp.Len() != 0 || tp.LookupTags(net.ParseIP("192.168.1.1")) != nil {
+		t.Errorf("store is not empty after removal")
+	}
+	// removed prefixes don't leave nodes behind
+	for i := 0; i < 100; i++ {
+		n := &net.IPNet{IP: net.IP{10, byte(i), 0, 0}, Mask: net.CIDRMask(16+i%16, 32)}
+		tp.Add(n, Tags{"a": "1"})
+		tp.Remove(n)
+	}
+	tp.Add(mustParseCIDR("2001:db8::/127"), nil)
+	tp.Remove(mustParseCIDR("2001:db8::/127"))
+	if tp.trie.v4.children != [2]*trieNode{} || tp.trie.v6.children != [2]*trieNode{} {
+		t.Errorf("empty nodes are not pruned after removal")
+	}
+	if err := tp.Add(&net.IPNet{IP: net.ParseIP("10.0.0.0"), Mask: net.IPMask{255, 0, 255, 0}}, nil); err == nil {
+		t.Errorf("didn't get an error for non-contiguous mask")
+	}
+	if err := tp.Add(nil, nil); err == nil {
+		t.Errorf("didn't get an error for nil network")
+	}
+}
+
+func TestTaggedPrefixesPolicies(t *testing.T) {
+	type testCase struct {
+		policy   InsertPolicy
+		networks string
+		tags     map[string]Tags
+		err      bool
+	}
+	cases := []testCase{
+		testCase{InsertMerge, "[10.0.0.0/8 10.1.0.0/16]", map[string]Tags{
+			"10.0.0.0/8":  Tags{"a": "1", "b": "2"},
+			"10.1.0.0/16": Tags{"b": "3"},
+		}, false},
+		testCase{InsertReject, "[10.0.0.0/8]", map[string]Tags{
+			"10.0.0.0/8": Tags{"a": "1"},
+		}, true},
+		testCase{InsertReplace, "[10.0.0.0/8 10.1.0.0/16]", map[string]Tags{
+			"10.0.0.0/8":  Tags{"b": "2"},
+			"10.1.0.0/16": Tags{"b": "3"},
+		}, false},
+		testCase{InsertSplit, "[10.0.0.0/16 10.1.0.0/16 10.2.0.0/15 10.4.0.0/14 10.8.0.0/13 10.16.0.0/12 10.32.0.0/11 10.64.0.0/10 10.128.0.0/9]",
+			map[string]Tags{
+				// stored subnets are more specific than the new network
+				"10.0.0.0/16":  Tags{"a": "1"},
+				"10.1.0.0/16":  Tags{"b": "3"},
+				"10.128.0.0/9": Tags{"a": "1"},
+			}, false},
+	}
+	for _, test := range cases {
+		tp := TaggedPrefixes{Policy: test.policy}
+		if err := tp.Add(mustParseCIDR("10.0.0.0/8"), Tags{"a": "1"}); err != nil {
+			t.Errorf("%v: unexpected error %v", test.policy, err)
+		}
+		var err error
+		if test.policy != InsertSplit {
+			err = tp.Add(mustParseCIDR("10.0.0.0/8"), Tags{"b": "2"})
+		} else {
+			err = tp.Add(mustParseCIDR("10.1.0.0/16"), Tags{"b": "3"})
+			if err == nil {
+				err = tp.Add(mustParseCIDR("10.0.0.0/8"), Tags{"b": "2"})
+			}
+		}
+		if err == nil && test.policy != InsertSplit {
+			err = tp.Add(mustParseCIDR("10.1.0.0/16"), Tags{"b": "3"})
+		}
+		if test.err != (err != nil) {
+			t.Errorf("%v: unexpected error %v", test.policy, err)
+		}
+		if test.policy == InsertReplace {
+			// replacing the supernet removes the subnet
+			tp.Add(mustParseCIDR("10.0.0.0/8"), Tags{"b": "2"})
+			if networks := fmt.Sprint(tp.Networks()); networks != "[10.0.0.0/8]" {
+				t.Errorf("%v: expecting the subnet to be removed, got %v", test.policy, networks)
+			}
+			tp.Add(mustParseCIDR("10.1.0.0/16"), Tags{"b": "3"})
+		}
+		if networks := fmt.Sprint(tp.Networks()); networks != test.networks {
+			t.Errorf("%v: expecting networks %v, got %v", test.policy, test.networks, networks)
+		}
+		if tp.Len() != len(tp.Networks()) {
+			t.Errorf("%v: Len %v doesn't match the number of networks", test.policy, tp.Len())
+		}
+		for network, tags := range test.tags {
+			if result := tp.Tags(mustParseCIDR(network)); !reflect.DeepEqual(tags, result) {
+				t.Errorf("%v: expecting tags %v, got %v for %v", test.policy, tags, result, network)
+			}
+		}
+	}
+}
+
+func TestTaggedPrefixesSplitSupernet(t *testing.T) {
+	tp := TaggedPrefixes{Policy: InsertSplit}
+	tp.Add(mustParseCIDR("192.168.0.0/22"), Tags{"owner": "infra"})
+	tp.Add(mustParseCIDR("192.168.1.0/24"), Tags{"owner": "team-a"})
+	if networks := fmt.Sprint(tp.Networks()); networks != "[192.168.0.0/24 192.168.1.0/24 192.168.2.0/23]" {
+		t.Errorf("unexpected networks %v", networks)
+	}
+	if tags := tp.LookupTags(net.ParseIP("192.168.3.1")); !reflect.DeepEqual(Tags{"owner": "infra"}, tags) {
+		t.Errorf("unexpected tags %v", tags)
+	}
+	if tags := tp.LookupTags(net.ParseIP("192.168.1.1")); !reflect.DeepEqual(Tags{"owner": "team-a"}, tags) {
+		t.Errorf("unexpected tags %v", tags)
+	}
+}
+
+func TestTaggedPrefixesSplitFamilies(t *testing.T) {
+	tp := TaggedPrefixes{Policy: InsertSplit}
+	tp.Add(mustParseCIDR("::/0"), Tags{"owner": "v6"})
+	tp.Add(mustParseCIDR("10.0.0.0/8"), Tags{"owner": "v4"})
+	if networks := fmt.Sprint(tp.Networks()); networks != "[10.0.0.0/8 ::/0]" {
+		t.Errorf("an IPv4 network split an IPv6 one: %v", networks)
+	}
+
+	tp.Add(mustParseCIDR("8000::/2"), Tags{"owner": "other"})
+	if networks := fmt.Sprint(tp.Networks()); networks != "[10.0.0.0/8 ::/1 8000::/2 c000::/2]" {
+		t.Errorf("unexpected networks %v", networks)
+	}
+	if tags := tp.LookupTags(net.ParseIP("::ffff:10.0.0.1")); !reflect.DeepEqual(Tags{"owner": "v4"}, tags) {
+		t.Errorf("unexpected tags %v", tags)
+	}
+
+	// the new network fills only the gaps between stored subnets
+	tp.Add(mustParseCIDR("::/0"), Tags{"owner": "all"})
+	if networks := fmt.Sprint(tp.Networks()); networks != "[10.0.0.0/8 ::/1 8000::/2 c000::/2]" {
+		t.Errorf("unexpected networks %v", networks)
+	}
+	tp.Add(mustParseCIDR("10.0.0.0/7"), Tags{"owner": "wide"})
+	if networks := fmt.Sprint(tp.Networks()); networks != "[10.0.0.0/8 11.0.0.0/8 ::/1 8000::/2 c000::/2]" {
+		t.Errorf("unexpected networks %v", networks)
+	}
+}
+
+func TestTaggedPrefixesMergeFunc(t *testing.T) {
+	tp := TaggedPrefixes{
+		MergeFunc: func(n *net.IPNet, existing, added Tags) Tags {
+			for k, v := range added {
+				if old, ok := existing[k]; ok {
+					v = old + "," + v
+				}
+				existing[k] = v
+			}
+			return existing
+		},
+	}
+	tp.Add(mustParseCIDR("10.0.0.0/8"), Tags{"owner": "a"})
+	tp.Add(mustParseCIDR("10.0.0.0/8"), Tags{"owner": "b", "env": "prod"})
+	if tags := tp.Tags(mustParseCIDR("10.0.0.0/8")); !reflect.DeepEqual(Tags{"owner": "a,b", "env": "prod"}, tags) {
+		t.Errorf("unexpected tags %v", tags)
+	}
+
+	// overlapping networks are merged through the callback as well
+	tp.Add(mustParseCIDR("10.1.0.0/16"), Tags{"owner": "c"})
+	tp.Add(mustParseCIDR("10.1.2.0/24"), Tags{"env": "dev"})
+	tp.Add(mustParseCIDR("10.0.0.0/7"), Tags{"owner": "d"})
+	type testCase struct {
+		network string
+		tags    Tags
+	}
+	cases := []testCase{
+		testCase{"10.0.0.0/7", Tags{"owner": "d"}},
+		testCase{"10.0.0.0/8", Tags{"owner": "a,b,d", "env": "prod"}},
+		testCase{"10.1.0.0/16", Tags{"owner": "a,b,c,d", "env": "prod"}},
+		testCase{"10.1.2.0/24", Tags{"owner": "a,b,c,d", "env": "prod,dev"}},
+	}
+	for _, test := range cases {
+		if tags := tp.Tags(mustParseCIDR(test.network)); !reflect.DeepEqual(test.tags, tags) {
+			t.Errorf("expecting %v, got %v for %v", test.tags, tags, test.network)
+		}
+	}
+	if tp.Len() != 4 {
+		t.Errorf("expecting 4 networks, got %v", tp.Len())
+	}
+	if err := (&TaggedPrefixes{Policy: InsertPolicy(42)}).Add(mustParseCIDR("10.0.0.0/8"), nil); err == nil {
+		t.Errorf("didn't get an error for unknown policy")
+	}
+}
+
+func ExampleTaggedPrefixes_LookupTags() {
+	var tp TaggedPrefixes
+	tp.Add(mustParseCIDR("10.0.0.0/8"), Tags{"owner": "infra", "env": "prod"})
+	tp.Add(mustParseCIDR("10.20.0.0/16"), Tags{"env": "staging"})
+
+	fmt.Println(tp.LookupTags(net.ParseIP("10.20.1.1")))
+	fmt.Println(tp.Networks())
+
+	// Output:
+	// map[env:staging owner:infra]
+	// [10.0.0.0/8 10.20.0.0/16]
+}
@@ -0,0 +1,161 @@
+// SPDX-License-Identifier: MIT-0
+
+package iputils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// HostResolver resolves host names to addresses.
+//
+// *net.Resolver satisfies this interface.
+type HostResolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// AddressFamily selects the addresses of which families are accepted.
+type AddressFamily int
+
+const (
+	// FamilyAny accepts both IPv4 and IPv6 addresses.
+	FamilyAny AddressFamily = iota
+
+	// FamilyIPv4 accepts IPv4 addresses only.
+	FamilyIPv4
+
+	// FamilyIPv6 accepts IPv6 addresses only, IPv4-mapped ones excluded.
+	FamilyIPv6
+)
+
+func (f AddressFamily) String() string {
+	switch f {
+	case FamilyAny:
+		return "any"
+	case FamilyIPv4:
+		return "IPv4"
+	case FamilyIPv6:
+		return "IPv6"
+	}
+	return fmt.Sprintf("AddressFamily(%d)", int(f))
+}
+
+// accepts reports whether ip belongs to the family
+func (f AddressFamily) accepts(ip net.IP) bool {
+	switch f {
+	case FamilyIPv4:
+		return IsIPv4(ip)
+	case FamilyIPv6:
+		return IsIPv6(ip)
+	}
+	return true
+}
+
+// Target is the result of resolving a single target specification.
+type Target struct {
+	// Spec is the specification as given
+	Spec string
+
+	// Host is true if the specification was resolved as a host name
+	Host bool
+
+	// Ranges are the addresses the specification stands for, sorted and
+	// joined as in IPSet
+	Ranges []IPRange
+
+	// Err is the reason why the specification produced no addresses
+	Err error
+}
+
+// ResolveTargets turns a list of targets as typed by users (addresses,
+// networks in CIDR notation, ranges and host names) into a set of addresses.
+// Host names are resolved with resolver, or with net.DefaultResolver if it
+// is nil.  Only the resolved addresses of the given family are used, e.g.
+// FamilyIPv4 for a tool which can't talk IPv6; addresses, networks and
+// ranges given explicitly are taken as is.
+//
+// Every specification gets a Target describing what it contributed to the
+// set, in the order of specs.  Failed specifications don't stop the
+// processing; they are reported both in their Target and in the returned
+// error, which joins all failures.
+func ResolveTargets(ctx context.Context, specs []string, resolver HostResolver, family AddressFamily) (*IPSet, []Target, error) {
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	result := &IPSet{}
+	targets := make([]Target, len(specs))
+	var errs []error
+	for i, spec := range specs {
+		target := resolveTarget(ctx, spec, resolver, family)
+		if target.Err != nil {
+			errs = append(errs, fmt.Errorf("target %q: %w", spec, target.Err))
+		}
+		for _, r := range target.Ranges {
+			result.AddRange(r)
+		}
+		targets[i] = target
+	}
+	return result, targets, errors.Join(errs...)
+}
+
+// resolveTarget parses or resolves a single target specification
+func resolveTarget(ctx context.Context, spec string, resolver HostResolver, family AddressFamily) Target {
+	target := Target{Spec: spec}
+	var set IPSet
+	element, err := ParseAny(spec)
+	switch {
+	case err == nil:
+		set.AddRange(element.Range)
+	case isHostName(strings.TrimSpace(spec)):
+		target.Host = true
+		addrs, lookupErr := resolver.LookupIPAddr(ctx, strings.TrimSpace(spec))
+		if lookupErr != nil {
+			target.Err = lookupErr
+			return target
+		}
+		for _, addr := range addrs {
+			if family.accepts(addr.IP) {
+				set.AddIP(addr.IP)
+			}
+		}
+		switch {
+		case set.RangeCount() > 0:
+		case len(addrs) > 0:
+			target.Err = fmt.Errorf("%w of family %v", ErrNoAddresses, family)
+		default:
+			target.Err = ErrNoAddresses
+		}
+	default:
+		target.Err = err
+	}
+	target.Ranges = set.Ranges()
+	return target
+}
+
+// isHostName reports whether s is syntactically a host name which is not an
+// ip address
+func isHostName(s string) bool {
+	s = strings.TrimSuffix(s, ".")
+	if s == "" || len(s) > 253 {
+		return false
+	}
+	hasLetter := false
+	for _, label := range strings.Split(s, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, c := range label {
+			switch {
+			case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', c == '_':
+				hasLetter = true
+			case '0' <= c && c <= '9', c == '-':
+			default:
+				return false
+			}
+		}
+	}
+	return hasLetter
+}
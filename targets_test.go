@@ -0,0 +1,156 @@
+// SPDX-License-Identifier: MIT-0
+
+package iputils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+)
+
+type fakeHostResolver map[string][]string
+
+func (r fakeHostResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	addrs, ok := r[host]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	var result []net.IPAddr
+	for _, addr := range addrs {
+		result = append(result, net.IPAddr{IP: net.ParseIP(addr)})
+	}
+	return result, nil
+}
+
+func TestResolveTargets(t *testing.T) {
+	resolver := fakeHostResolver{
+		"web.example.com": []string{"192.0.2.10", "2001:db8::10", "192.0.2.11"},
+		"db-1.example":    []string{"192.0.2.5"},
+		"empty.example":   nil,
+	}
+	specs := []string{
+		"192.0.2.1",
+		"198.51.100.0/30",
+		"192.0.2.3-192.0.2.6",
+		"web.example.com",
+		" db-1.example ",
+		"missing.example",
+		"empty.example",
+		"10.0.0.300",
+		"2001:db8::/127",
+	}
+	set, targets, err := ResolveTargets(context.Background(), specs, resolver, FamilyAny)
+
+	expected := "[192.0.2.1-192.0.2.1 192.0.2.3-192.0.2.6 192.0.2.10-192.0.2.11 198.51.100.0-198.51.100.3 2001:db8::-2001:db8::1 2001:db8::10-2001:db8::10]"
+	if set.String() != expected {
+		t.Errorf("expecting %v, got %v", expected, set)
+	}
+
+	type testCase struct {
+		host   bool
+		ranges string
+		err    bool
+	}
+	cases := []testCase{
+		testCase{false, "[192.0.2.1-192.0.2.1]", false},
+		testCase{false, "[198.51.100.0-198.51.100.3]", false},
+		testCase{false, "[192.0.2.3-192.0.2.6]", false},
+		testCase{true, "[192.0.2.10-192.0.2.11 2001:db8::10-2001:db8::10]", false},
+		testCase{true, "[192.0.2.5-192.0.2.5]", false},
+		testCase{true, "[]", true},
+		testCase{true, "[]", true},
+		testCase{false, "[]", true},
+		testCase{false, "[2001:db8::-2001:db8::1]", false},
+	}
+	if len(targets) != len(cases) {
+		t.Fatalf("expecting %v targets, got %v", len(cases), len(targets))
+	}
+	for i, test := range cases {
+		target := targets[i]
+		if target.Spec != specs[i] || target.Host != test.host || fmt.Sprint(target.Ranges) != test.ranges || (target.Err != nil) != test.err {
+			t.Errorf("unexpected target %+v for %q", target, specs[i])
+		}
+	}
+
+	var dnsErr *net.DNSError
+	if !errors.As(err, &dnsErr) || dnsErr.Name != "missing.example" {
+		t.Errorf("expecting the DNS error to be wrapped, got %v", err)
+	}
+	if !errors.Is(err, ErrInvalidIP) {
+		t.Errorf("expecting the parse error to be wrapped, got %v", err)
+	}
+	if !errors.Is(targets[6].Err, ErrNoAddresses) {
+		t.Errorf("expecting no addresses error, got %v", targets[6].Err)
+	}
+}
+
+func TestResolveTargetsFamily(t *testing.T) {
+	resolver := fakeHostResolver{
+		"web.example.com": []string{"192.0.2.10", "2001:db8::10", "::ffff:192.0.2.11"},
+		"v6.example":      []string{"2001:db8::20"},
+	}
+	specs := []string{"web.example.com", "v6.example", "2001:db8::1", "192.0.2.1"}
+
+	type testCase struct {
+		family AddressFamily
+		result string
+		failed string
+	}
+	cases := []testCase{
+		testCase{FamilyAny, "[192.0.2.1-192.0.2.1 192.0.2.10-192.0.2.11 2001:db8::1-2001:db8::1 2001:db8::10-2001:db8::10 2001:db8::20-2001:db8::20]", ""},
+		testCase{FamilyIPv4, "[192.0.2.1-192.0.2.1 192.0.2.10-192.0.2.11 2001:db8::1-2001:db8::1]", "v6.example"},
+		testCase{FamilyIPv6, "[192.0.2.1-192.0.2.1 2001:db8::1-2001:db8::1 2001:db8::10-2001:db8::10 2001:db8::20-2001:db8::20]", ""},
+	}
+	for _, test := range cases {
+		set, targets, err := ResolveTargets(context.Background(), specs, resolver, test.family)
+		if set.String() != test.result {
+			t.Errorf("%v: expecting %v, got %v", test.family, test.result, set)
+		}
+		failed := ""
+		for _, target := range targets {
+			if target.Err != nil {
+				failed += target.Spec
+				if !errors.Is(target.Err, ErrNoAddresses) {
+					t.Errorf("%v: unexpected error %v", test.family, target.Err)
+				}
+			}
+		}
+		if failed != test.failed || (err != nil) != (test.failed != "") {
+			t.Errorf("%v: unexpected failures %q, %v", test.family, failed, err)
+		}
+	}
+}
+
+func TestResolveTargetsNoErrors(t *testing.T) {
+	set, targets, err := ResolveTargets(context.Background(), []string{"10.0.0.0/31"}, nil, FamilyAny)
+	if err != nil || len(targets) != 1 || set.Count().Int64() != 2 {
+		t.Errorf("unexpected result %v, %v, %v", set, targets, err)
+	}
+}
+
+func TestIsHostName(t *testing.T) {
+	type testCase struct {
+		s      string
+		isHost bool
+	}
+	cases := []testCase{
+		testCase{"example.com", true},
+		testCase{"example.com.", true},
+		testCase{"my-host", true},
+		testCase{"_service.example", true},
+		testCase{"1password.com", true},
+		testCase{"10.0.0.300", false},
+		testCase{"-host.example", false},
+		testCase{"host..example", false},
+		testCase{"2001:db8::1", false},
+		testCase{"10.0.0.0/8", false},
+		testCase{"", false},
+	}
+	for _, test := range cases {
+		if isHost := isHostName(test.s); isHost != test.isHost {
+			t.Errorf("expecting %v, got %v for %q", test.isHost, isHost, test.s)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+// SPDX-License-Identifier: MIT-0
+
+package iputils
+
+import (
+	"context"
+	"encoding"
+	"fmt"
+	"math"
+	"net"
+	"time"
+)
+
+// Limiter paces iteration.  Wait blocks until the next step is allowed.
+//
+// *rate.Limiter from golang.org/x/time/rate satisfies this interface.
+type Limiter interface {
+	Wait(ctx context.Context) error
+}
+
+// GetThrottledIterator returns an iterator producing the same addresses as
+// iter but not faster than rate addresses per second.  The iteration stops
+// when ctx is cancelled.  The rate must be positive; rates so small that the
+// interval between addresses doesn't fit time.Duration are clamped to the
+// longest interval.
+func GetThrottledIterator(ctx context.Context, iter IPRangeIterator, rate float64) (IPRangeIterator, error) {
+	if !(rate > 0) {
+		return nil, fmt.Errorf("%w: rate %v is not positive", ErrOutOfRange, rate)
+	}
+	return GetLimitedIterator(ctx, iter, newIntervalLimiter(rate)), nil
+}
+
+// GetLimitedIterator returns an iterator producing the same addresses as
+// iter, waiting on limiter before every address.  When the limiter returns
+// an error (for example because ctx is cancelled), the iteration stops.
+//
+// The returned iterator implements encoding.BinaryMarshaler by saving the
+// state of iter, which fails if iter doesn't implement it.  A scan stopped by
+// the limiter can be saved and later continued with ResumeIPRangeIterator
+// from the address it was waiting for.
+func GetLimitedIterator(ctx context.Context, iter IPRangeIterator, limiter Limiter) IPRangeIterator {
+	return &limitedIterator{ctx: ctx, iter: iter, limiter: limiter}
+}
+
+// unreader is implemented by iterators which can take back the address
+// they have just produced
+type unreader interface {
+	unread(ip net.IP)
+}
+
+type limitedIterator struct {
+	ctx     context.Context
+	iter    IPRangeIterator
+	limiter Limiter
+	last    net.IP
+	stopped bool
+
+	// lost is set when the limiter failed after an address was taken from
+	// iter and iter couldn't take it back
+	lost bool
+}
+
+func (iter *limitedIterator) Next() (ip net.IP, ok bool) {
+	if iter.stopped {
+		return iter.last, false
+	}
+	// the inner iterator is checked first, so the end of the iteration
+	// doesn't wait on the limiter
+	ip, ok = iter.iter.Next()
+	if !ok {
+		return ip, false
+	}
+	if err := iter.limiter.Wait(iter.ctx); err != nil {
+		iter.stopped = true
+		if u, canUnread := iter.iter.(unreader); canUnread {
+			u.unread(ip)
+		} else {
+			iter.lost = true
+		}
+		return iter.last, false
+	}
+	iter.last = ip
+	return ip, true
+}
+
+// MarshalBinary returns the state of the wrapped iterator.
+func (iter *limitedIterator) MarshalBinary() ([]byte, error) {
+	m, ok := iter.iter.(encoding.BinaryMarshaler)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T can't be saved", ErrInvalidEncoding, iter.iter)
+	}
+	if iter.lost {
+		return nil, fmt.Errorf("%w: the address pending when the limiter failed can't be saved", ErrInvalidEncoding)
+	}
+	return m.MarshalBinary()
+}
+
+// intervalLimiter allows one event per interval without bursts.
+type intervalLimiter struct {
+	interval time.Duration
+	next     time.Time
+
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+func newIntervalLimiter(rate float64) *intervalLimiter {
+	interval := time.Duration(math.MaxInt64)
+	if ns := float64(time.Second) / rate; ns < float64(math.MaxInt64) {
+		interval = time.Duration(ns)
+	}
+	return &intervalLimiter{
+		interval: interval,
+		now:      time.Now,
+		sleep:    sleepContext,
+	}
+}
+
+func (l *intervalLimiter) Wait(ctx context.Context) error {
+	now := l.now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	if err := l.sleep(ctx, l.next.Sub(now)); err != nil {
+		return err
+	}
+	l.next = l.next.Add(l.interval)
+	return nil
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,184 @@
+// SPDX-License-Identifier: MIT-0
+
+package iputils
+
+import (
+	"context"
+	"encoding"
+	"errors"
+	"math"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestIntervalLimiter(t *testing.T) {
+	now := time.Unix(1000, 0)
+	var slept []time.Duration
+	limiter := newIntervalLimiter(4)
+	limiter.now = func() time.Time { return now }
+	limiter.sleep = func(ctx context.Context, d time.Duration) error {
+		slept = append(slept, d)
+		now = now.Add(d)
+		return nil
+	}
+
+	for i := 0; i < 3; i++ {
+		limiter.Wait(context.Background())
+	}
+	// a consumer being slow doesn't produce a burst afterwards
+	now = now.Add(time.Second)
+	limiter.Wait(context.Background())
+	limiter.Wait(context.Background())
+
+	expected := []time.Duration{0, 250 * time.Millisecond, 250 * time.Millisecond, 0, 250 * time.Millisecond}
+	if len(slept) != len(expected) {
+		t.Fatalf("expecting sleeps %v, got %v", expected, slept)
+	}
+	for i := range expected {
+		if expected[i] != slept[i] {
+			t.Errorf("expecting sleeps %v, got %v", expected, slept)
+			break
+		}
+	}
+}
+
+func TestThrottledIterator(t *testing.T) {
+	iter, err := GetThrottledIterator(context.Background(), GetIPRangeIterator(net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.5")), 100)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	limiter := iter.(*limitedIterator).limiter.(*intervalLimiter)
+	now := time.Unix(1000, 0)
+	var slept time.Duration
+	limiter.now = func() time.Time { return now }
+	limiter.sleep = func(ctx context.Context, d time.Duration) error {
+		slept += d
+		now = now.Add(d)
+		return nil
+	}
+
+	count := 0
+	for _, ok := iter.Next(); ok; _, ok = iter.Next() {
+		count++
+	}
+	if count != 5 {
+		t.Errorf("expecting 5 addresses, got %v", count)
+	}
+	if slept != 40*time.Millisecond {
+		t.Errorf("expecting to sleep 40ms, slept %v", slept)
+	}
+}
+
+func TestThrottledIteratorRates(t *testing.T) {
+	for _, rate := range []float64{0, -1, math.NaN()} {
+		if _, err := GetThrottledIterator(context.Background(), GetIPRangeIterator(nil, nil), rate); !errors.Is(err, ErrOutOfRange) {
+			t.Errorf("expecting an out of range error for rate %v, got %v", rate, err)
+		}
+	}
+	type testCase struct {
+		rate     float64
+		interval time.Duration
+	}
+	cases := []testCase{
+		testCase{4, 250 * time.Millisecond},
+		testCase{math.Inf(1), 0},
+		testCase{1e-9, 1e18 * time.Nanosecond},
+		testCase{1e-10, time.Duration(math.MaxInt64)},
+		testCase{math.SmallestNonzeroFloat64, time.Duration(math.MaxInt64)},
+	}
+	for _, test := range cases {
+		if interval := newIntervalLimiter(test.rate).interval; interval != test.interval {
+			t.Errorf("expecting interval %v, got %v for rate %v", test.interval, interval, test.rate)
+		}
+	}
+}
+
+func TestThrottledIteratorCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	iter, _ := GetThrottledIterator(ctx, GetIPRangeIterator(net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.5")), 1e-3)
+	if _, ok := iter.Next(); !ok {
+		t.Fatalf("expecting the first address without waiting")
+	}
+	cancel()
+	if ip, ok := iter.Next(); ok {
+		t.Errorf("iterator produced %v after cancellation", ip)
+	}
+}
+
+type failingLimiter struct {
+	allowed int
+	calls   int
+}
+
+func (l *failingLimiter) Wait(ctx context.Context) error {
+	l.calls++
+	if l.allowed == 0 {
+		return errors.New("limit reached")
+	}
+	l.allowed--
+	return nil
+}
+
+func TestLimitedIterator(t *testing.T) {
+	iter := GetLimitedIterator(context.Background(),
+		GetIPRangeIterator(net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.5")), &failingLimiter{allowed: 2})
+	expected := []net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2")}
+	for i := range expected {
+		ip, ok := iter.Next()
+		if !ok || !expected[i].Equal(ip) {
+			t.Fatalf("expecting (%v, true), got (%v, %v)", expected[i], ip, ok)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if ip, ok := iter.Next(); ok || !expected[1].Equal(ip) {
+			t.Errorf("expecting (%v, false) after limiter failure, got (%v, %v)", expected[1], ip, ok)
+		}
+	}
+
+	// the stopped scan continues from the address it was waiting for
+	state, err := iter.(encoding.BinaryMarshaler).MarshalBinary()
+	if err != nil {
+		t.Fatalf("failed to save the stopped iterator: %v", err)
+	}
+	resumed, err := ResumeIPRangeIterator(state)
+	if err != nil {
+		t.Fatalf("failed to resume the stopped iterator: %v", err)
+	}
+	if ip, ok := resumed.Next(); !ok || !ip.Equal(net.ParseIP("10.0.0.3")) {
+		t.Errorf("expecting (10.0.0.3, true) after resuming, got (%v, %v)", ip, ok)
+	}
+}
+
+func TestLimitedIteratorEnd(t *testing.T) {
+	limiter := &failingLimiter{allowed: 10}
+	iter := GetLimitedIterator(context.Background(),
+		GetIPRangeIterator(net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.3")), limiter)
+	for _, ok := iter.Next(); ok; _, ok = iter.Next() {
+	}
+	iter.Next()
+	if limiter.calls != 3 {
+		t.Errorf("expecting 3 waits for 3 addresses, got %v", limiter.calls)
+	}
+}
+
+func TestLimitedIteratorMarshalFaults(t *testing.T) {
+	inner := &sliceIPIterator{ips: []net.IP{net.ParseIP("10.0.0.1")}}
+	iter := GetLimitedIterator(context.Background(), inner, &failingLimiter{})
+	if _, err := iter.(encoding.BinaryMarshaler).MarshalBinary(); !errors.Is(err, ErrInvalidEncoding) {
+		t.Errorf("expecting an invalid encoding error, got %v", err)
+	}
+}
+
+type sliceIPIterator struct {
+	ips []net.IP
+}
+
+func (iter *sliceIPIterator) Next() (net.IP, bool) {
+	if len(iter.ips) == 0 {
+		return nil, false
+	}
+	ip := iter.ips[0]
+	iter.ips = iter.ips[1:]
+	return ip, true
+}
@@ -0,0 +1,276 @@
+// SPDX-License-Identifier: MIT-0
+
+package iputils
+
+import (
+	"net"
+)
+
+// prefixTrie is a binary trie of network prefixes.
+//
+// IPv4 and IPv6 prefixes are kept in separate tries, the same way IPSet
+// never joins the families: an IPv6 prefix never contains IPv4 addresses.
+// A network counts as IPv4 when ToPrefix converts it to an IPv4 prefix.
+type prefixTrie struct {
+	v4 trieNode
+	v6 trieNode
+}
+
+type trieNode struct {
+	children [2]*trieNode
+
+	// network is set when a prefix terminates at this node
+	network *net.IPNet
+	value   interface{}
+}
+
+// networkKey returns the network address in the form of its family
+// (4-byte for IPv4, 16-byte for IPv6) and the prefix length.
+func networkKey(n *net.IPNet) (key net.IP, length int, err error) {
+	prefix, err := ToPrefix(n)
+	if err != nil {
+		return nil, 0, err
+	}
+	return prefix.Addr().AsSlice(), prefix.Bits(), nil
+}
+
+// rootFor returns the root of the trie for keys of the size
+func (t *prefixTrie) rootFor(key net.IP) *trieNode {
+	if len(key) == IPv4Size {
+		return &t.v4
+	}
+	return &t.v6
+}
+
+// canonicalNetwork returns a copy of the network with host bits cleared in
+// the form FromPrefix produces.  The network must be valid for ToPrefix.
+func canonicalNetwork(n *net.IPNet) *net.IPNet {
+	prefix, _ := ToPrefix(n)
+	return FromPrefix(prefix)
+}
+
+func keyBit(key net.IP, i int) int {
+	return int(key[i/8]>>(7-uint(i%8))) & 1
+}
+
+// keyNetwork returns the network of the first length bits of key with the
+// bit at length-1 set to b.
+func keyNetwork(key net.IP, length int, b int) *net.IPNet {
+	mask := net.CIDRMask(length, len(key)*8)
+	ip := key.Mask(mask)
+	i := length - 1
+	ip[i/8] = ip[i/8]&^(1<<(7-uint(i%8))) | byte(b)<<(7-uint(i%8))
+	return &net.IPNet{IP: ip, Mask: mask}
+}
+
+// splitOff returns the disjoint networks covering the addresses of the
+// supernet with the prefix length from which don't belong to the network.
+func splitOff(n *net.IPNet, from int) []*net.IPNet {
+	key, length, err := networkKey(n)
+	if err != nil {
+		return nil
+	}
+	var result []*net.IPNet
+	for i := from; i < length; i++ {
+		result = append(result, keyNetwork(key, i+1, 1-keyBit(key, i)))
+	}
+	return result
+}
+
+// insert returns the node of the network, creating it if needed.
+func (t *prefixTrie) insert(n *net.IPNet) (*trieNode, error) {
+	key, length, err := networkKey(n)
+	if err != nil {
+		return nil, err
+	}
+	node := t.rootFor(key)
+	for i := 0; i < length; i++ {
+		b := keyBit(key, i)
+		if node.children[b] == nil {
+			node.children[b] = &trieNode{}
+		}
+		node = node.children[b]
+	}
+	if node.network == nil {
+		node.network = canonicalNetwork(n)
+	}
+	return node, nil
+}
+
+// get returns the node where the network terminates or nil.
+func (t *prefixTrie) get(n *net.IPNet) *trieNode {
+	path := t.path(n)
+	if path == nil {
+		return nil
+	}
+	node := path[len(path)-1]
+	if node.network == nil {
+		return nil
+	}
+	return node
+}
+
+// path returns the nodes from the root to the node of the network or nil if
+// there is no such node.
+func (t *prefixTrie) path(n *net.IPNet) []*trieNode {
+	key, length, err := networkKey(n)
+	if err != nil {
+		return nil
+	}
+	path := make([]*trieNode, 0, length+1)
+	node := t.rootFor(key)
+	for i := 0; i < length && node != nil; i++ {
+		path = append(path, node)
+		node = node.children[keyBit(key, i)]
+	}
+	if node == nil {
+		return nil
+	}
+	return append(path, node)
+}
+
+// remove deletes the network from the trie and reports whether it was there.
+// Nodes left without networks and children are pruned.
+func (t *prefixTrie) remove(n *net.IPNet) bool {
+	path := t.path(n)
+	if path == nil || path[len(path)-1].network == nil {
+		return false
+	}
+	node := path[len(path)-1]
+	node.network = nil
+	node.value = nil
+	for i := len(path) - 1; i > 0; i-- {
+		node := path[i]
+		if node.network != nil || node.children[0] != nil || node.children[1] != nil {
+			break
+		}
+		parent := path[i-1]
+		if parent.children[0] == node {
+			parent.children[0] = nil
+		} else {
+			parent.children[1] = nil
+		}
+	}
+	return true
+}
+
+// matches returns the nodes of all networks containing ip ordered from
+// the least specific to the most specific one.
+func (t *prefixTrie) matches(ip net.IP) []*trieNode {
+	key := ip.To4()
+	if key == nil {
+		key = ip.To16()
+	}
+	if key == nil {
+		return nil
+	}
+	var result []*trieNode
+	node := t.rootFor(key)
+	for i := 0; node != nil; i++ {
+		if node.network != nil {
+			result = append(result, node)
+		}
+		if i == len(key)*8 {
+			break
+		}
+		node = node.children[keyBit(key, i)]
+	}
+	return result
+}
+
+// supernets returns the nodes of all networks strictly containing the
+// network ordered from the least specific to the most specific one.
+func (t *prefixTrie) supernets(n *net.IPNet) []*trieNode {
+	key, length, err := networkKey(n)
+	if err != nil {
+		return nil
+	}
+	var result []*trieNode
+	node := t.rootFor(key)
+	for i := 0; i < length && node != nil; i++ {
+		if node.network != nil {
+			result = append(result, node)
+		}
+		node = node.children[keyBit(key, i)]
+	}
+	return result
+}
+
+// subnets returns the nodes of all networks strictly contained in the
+// network in ascending order.
+func (t *prefixTrie) subnets(n *net.IPNet) []*trieNode {
+	key, length, err := networkKey(n)
+	if err != nil {
+		return nil
+	}
+	node := t.rootFor(key)
+	for i := 0; i < length && node != nil; i++ {
+		node = node.children[keyBit(key, i)]
+	}
+	if node == nil {
+		return nil
+	}
+	var result []*trieNode
+	node.walk(func(child *trieNode) bool {
+		if child != node {
+			result = append(result, child)
+		}
+		return true
+	})
+	return result
+}
+
+// uncovered returns the disjoint networks covering the addresses of the
+// network which don't belong to any network strictly contained in it.
+func (t *prefixTrie) uncovered(n *net.IPNet) []*net.IPNet {
+	key, length, err := networkKey(n)
+	if err != nil {
+		return nil
+	}
+	node := t.rootFor(key)
+	for i := 0; i < length && node != nil; i++ {
+		node = node.children[keyBit(key, i)]
+	}
+	if node == nil || node.children == [2]*trieNode{} {
+		return []*net.IPNet{canonicalNetwork(n)}
+	}
+	// nodes below the network without children always hold networks, as
+	// empty ones are pruned
+	var result []*net.IPNet
+	var collect func(node *trieNode, key net.IP, depth int)
+	collect = func(node *trieNode, key net.IP, depth int) {
+		if depth > length && node.network != nil {
+			return
+		}
+		for b, child := range node.children {
+			childNetwork := keyNetwork(key, depth+1, b)
+			if child == nil {
+				result = append(result, childNetwork)
+			} else {
+				collect(child, childNetwork.IP, depth+1)
+			}
+		}
+	}
+	collect(node, key, length)
+	return result
+}
+
+// walk calls fn for every network in the trie, IPv4 networks first, in
+// ascending order.  Walking stops when fn returns false.
+func (t *prefixTrie) walk(fn func(node *trieNode) bool) {
+	if t.v4.walk(fn) {
+		t.v6.walk(fn)
+	}
+}
+
+func (node *trieNode) walk(fn func(node *trieNode) bool) bool {
+	if node.network != nil && !fn(node) {
+		return false
+	}
+	for _, child := range node.children {
+		if child != nil && !child.walk(fn) {
+			return false
+		}
+	}
+	return true
+}
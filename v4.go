@@ -0,0 +1,225 @@
+// SPDX-License-Identifier: MIT-0
+
+package iputils
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sort"
+)
+
+// maxV4 is the biggest IPv4 address as a number
+const maxV4 = ^uint32(0)
+
+// V4FromIP returns the IPv4 address (in the 4-byte or IPv4-mapped form) as
+// a number and true.  For other addresses false is returned.
+func V4FromIP(ip net.IP) (uint32, bool) {
+	ip4 := ip.To4()
+	if ip4 == nil {
+		return 0, false
+	}
+	return binary.BigEndian.Uint32(ip4), true
+}
+
+// V4ToIP returns the 4-byte address with the value v.
+func V4ToIP(v uint32) net.IP {
+	ip := make(net.IP, IPv4Size)
+	binary.BigEndian.PutUint32(ip, v)
+	return ip
+}
+
+// V4Range is a range of IPv4 addresses from First to Last (inclusive) stored
+// as numbers.  It is a fast path for IPv4-only workloads: its methods don't
+// allocate and don't use big.Int.  A range with First bigger than Last is
+// empty.
+type V4Range struct {
+	First uint32
+	Last  uint32
+}
+
+// V4RangeFromIPRange converts an IPv4 range and returns true.  If the range
+// is not a valid IPv4 range, false is returned.
+func V4RangeFromIPRange(r IPRange) (V4Range, bool) {
+	first, ok1 := V4FromIP(r.First)
+	last, ok2 := V4FromIP(r.Last)
+	if !ok1 || !ok2 || first > last {
+		return V4Range{}, false
+	}
+	return V4Range{first, last}, true
+}
+
+// IPRange converts the range to IPRange with 4-byte addresses.
+func (r V4Range) IPRange() IPRange {
+	return IPRange{V4ToIP(r.First), V4ToIP(r.Last)}
+}
+
+// Contains reports whether v belongs to the range.
+func (r V4Range) Contains(v uint32) bool {
+	return r.First <= v && v <= r.Last
+}
+
+// Size returns the number of addresses in the range.
+func (r V4Range) Size() uint64 {
+	if r.First > r.Last {
+		return 0
+	}
+	return uint64(r.Last-r.First) + 1
+}
+
+// Each calls fn for every address of the range in ascending order.  The
+// iteration stops when fn returns false.
+func (r V4Range) Each(fn func(v uint32) bool) {
+	if r.First > r.Last {
+		return
+	}
+	for v := r.First; fn(v) && v != r.Last; v++ {
+	}
+}
+
+func (r V4Range) String() string {
+	return fmt.Sprintf("%v-%v", V4ToIP(r.First), V4ToIP(r.Last))
+}
+
+// V4Set is a set of IPv4 addresses, the IPv4-only counterpart of IPSet.
+//
+// The zero value is an empty set ready to use.
+type V4Set struct {
+	// ranges are sorted, non-overlapping and non-adjacent
+	ranges []V4Range
+}
+
+// NewV4Set returns a set of the addresses of the ranges.
+func NewV4Set(ranges ...V4Range) *V4Set {
+	s := &V4Set{}
+	for _, r := range ranges {
+		s.Add(r)
+	}
+	return s
+}
+
+// Add adds the addresses of the range to the set.
+func (s *V4Set) Add(r V4Range) {
+	if r.First > r.Last {
+		return
+	}
+	// i is the first range which overlaps with r, touches it or follows it
+	i := sort.Search(len(s.ranges), func(i int) bool {
+		return s.ranges[i].Last >= r.First || s.ranges[i].Last+1 == r.First
+	})
+	j := i
+	for ; j < len(s.ranges) && (r.Last == maxV4 || s.ranges[j].First <= r.Last+1); j++ {
+		if s.ranges[j].First < r.First {
+			r.First = s.ranges[j].First
+		}
+		if s.ranges[j].Last > r.Last {
+			r.Last = s.ranges[j].Last
+		}
+	}
+	if i == j {
+		s.ranges = append(s.ranges, V4Range{})
+		copy(s.ranges[i+1:], s.ranges[i:])
+		s.ranges[i] = r
+		return
+	}
+	s.ranges[i] = r
+	s.ranges = append(s.ranges[:i+1], s.ranges[j:]...)
+}
+
+// Contains reports whether v belongs to the set.
+func (s *V4Set) Contains(v uint32) bool {
+	i := sort.Search(len(s.ranges), func(i int) bool { return s.ranges[i].Last >= v })
+	return i < len(s.ranges) && s.ranges[i].First <= v
+}
+
+// Size returns the number of addresses in the set.
+func (s *V4Set) Size() uint64 {
+	var result uint64
+	for _, r := range s.ranges {
+		result += r.Size()
+	}
+	return result
+}
+
+// Ranges returns the contents of the set as a sorted list of ranges.
+func (s *V4Set) Ranges() []V4Range {
+	return append([]V4Range(nil), s.ranges...)
+}
+
+// Union returns a new set with the addresses of both sets.
+func (s *V4Set) Union(other *V4Set) *V4Set {
+	result := &V4Set{ranges: make([]V4Range, 0, len(s.ranges)+len(other.ranges))}
+	i, j := 0, 0
+	for i < len(s.ranges) || j < len(other.ranges) {
+		var r V4Range
+		if j == len(other.ranges) || i < len(s.ranges) && s.ranges[i].First < other.ranges[j].First {
+			r, i = s.ranges[i], i+1
+		} else {
+			r, j = other.ranges[j], j+1
+		}
+		// the ranges come sorted, so r can only extend the last one
+		if n := len(result.ranges); n > 0 && (result.ranges[n-1].Last == maxV4 || r.First <= result.ranges[n-1].Last+1) {
+			if r.Last > result.ranges[n-1].Last {
+				result.ranges[n-1].Last = r.Last
+			}
+			continue
+		}
+		result.ranges = append(result.ranges, r)
+	}
+	return result
+}
+
+// Intersect returns a new set with the addresses belonging to both sets.
+func (s *V4Set) Intersect(other *V4Set) *V4Set {
+	result := &V4Set{}
+	for i, j := 0, 0; i < len(s.ranges) && j < len(other.ranges); {
+		a, b := s.ranges[i], other.ranges[j]
+		r := V4Range{a.First, a.Last}
+		if b.First > r.First {
+			r.First = b.First
+		}
+		if b.Last < r.Last {
+			r.Last = b.Last
+		}
+		if r.First <= r.Last {
+			result.ranges = append(result.ranges, r)
+		}
+		if a.Last < b.Last {
+			i++
+		} else {
+			j++
+		}
+	}
+	return result
+}
+
+// Subtract returns a new set with the addresses of the set which don't
+// belong to the other set.
+func (s *V4Set) Subtract(other *V4Set) *V4Set {
+	result := &V4Set{}
+	j := 0
+	for _, r := range s.ranges {
+		for j < len(other.ranges) && other.ranges[j].Last < r.First {
+			j++
+		}
+		k := j
+		for ; k < len(other.ranges) && other.ranges[k].First <= r.Last; k++ {
+			if other.ranges[k].First > r.First {
+				result.ranges = append(result.ranges, V4Range{r.First, other.ranges[k].First - 1})
+			}
+			if other.ranges[k].Last >= r.Last {
+				r.First, r.Last = 1, 0
+				break
+			}
+			r.First = other.ranges[k].Last + 1
+		}
+		if r.First <= r.Last {
+			result.ranges = append(result.ranges, r)
+		}
+	}
+	return result
+}
+
+func (s *V4Set) String() string {
+	return fmt.Sprint(s.ranges)
+}
@@ -0,0 +1,217 @@
+// SPDX-License-Identifier: MIT-0
+
+package iputils
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+	"testing"
+)
+
+// mustParseV4Range parses an IPv4 range like "10.0.0.1-10.0.0.5"
+func mustParseV4Range(s string) V4Range {
+	r, ok := V4RangeFromIPRange(mustParseRanges(s)[0])
+	if !ok {
+		panic("not an IPv4 range: " + s)
+	}
+	return r
+}
+
+func TestV4Conversions(t *testing.T) {
+	if v, ok := V4FromIP(net.ParseIP("192.0.2.1")); !ok || v != 0xc0000201 {
+		t.Errorf("unexpected conversion result %x, %v", v, ok)
+	}
+	if _, ok := V4FromIP(net.ParseIP("2001:db8::1")); ok {
+		t.Errorf("IPv6 address converted to IPv4")
+	}
+	if ip := V4ToIP(0xc0000201); len(ip) != IPv4Size || ip.String() != "192.0.2.1" {
+		t.Errorf("unexpected address %#v", ip)
+	}
+	if r, ok := V4RangeFromIPRange(IPRange{net.ParseIP("10.0.0.5"), net.ParseIP("10.0.0.1")}); ok {
+		t.Errorf("reversed range converted to %v", r)
+	}
+	if r := mustParseV4Range("10.0.0.1-10.0.0.5"); r.IPRange().String() != "10.0.0.1-10.0.0.5" || r.Size() != 5 {
+		t.Errorf("unexpected range %v of size %v", r, r.Size())
+	}
+	if size := (V4Range{0, maxV4}).Size(); size != 1<<32 {
+		t.Errorf("unexpected size of the whole space %v", size)
+	}
+}
+
+func TestV4RangeEach(t *testing.T) {
+	type testCase struct {
+		r     V4Range
+		limit int
+		seq   string
+	}
+	cases := []testCase{
+		testCase{mustParseV4Range("10.0.0.254-10.0.1.1"), 10, "[10.0.0.254 10.0.0.255 10.0.1.0 10.0.1.1]"},
+		testCase{mustParseV4Range("255.255.255.254-255.255.255.255"), 10, "[255.255.255.254 255.255.255.255]"},
+		testCase{mustParseV4Range("10.0.0.1-10.0.0.9"), 2, "[10.0.0.1 10.0.0.2]"},
+		testCase{V4Range{5, 4}, 10, "[]"},
+	}
+	for _, test := range cases {
+		seq := []net.IP{}
+		test.r.Each(func(v uint32) bool {
+			seq = append(seq, V4ToIP(v))
+			return len(seq) < test.limit
+		})
+		if fmt.Sprint(seq) != test.seq {
+			t.Errorf("expecting %v, got %v for %v", test.seq, seq, test.r)
+		}
+	}
+}
+
+func TestV4SetOperations(t *testing.T) {
+	a := NewV4Set(
+		mustParseV4Range("10.0.0.0-10.0.0.255"),
+		mustParseV4Range("10.0.2.0-10.0.2.255"),
+		mustParseV4Range("10.0.1.0-10.0.1.255"),
+		mustParseV4Range("255.255.255.0-255.255.255.255"),
+	)
+	b := NewV4Set(
+		mustParseV4Range("10.0.0.128-10.0.0.129"),
+		mustParseV4Range("10.0.2.255-10.0.3.10"),
+		mustParseV4Range("0.0.0.0-0.0.0.255"),
+	)
+	type testCase struct {
+		name   string
+		set    *V4Set
+		result string
+	}
+	cases := []testCase{
+		testCase{"a", a, "[10.0.0.0-10.0.2.255 255.255.255.0-255.255.255.255]"},
+		testCase{"union", a.Union(b), "[0.0.0.0-0.0.0.255 10.0.0.0-10.0.3.10 255.255.255.0-255.255.255.255]"},
+		testCase{"intersect", a.Intersect(b), "[10.0.0.128-10.0.0.129 10.0.2.255-10.0.2.255]"},
+		testCase{"subtract", a.Subtract(b), "[10.0.0.0-10.0.0.127 10.0.0.130-10.0.2.254 255.255.255.0-255.255.255.255]"},
+		testCase{"reverse subtract", b.Subtract(a), "[0.0.0.0-0.0.0.255 10.0.3.0-10.0.3.10]"},
+		testCase{"empty", new(V4Set).Union(new(V4Set)), "[]"},
+	}
+	for _, test := range cases {
+		if result := test.set.String(); result != test.result {
+			t.Errorf("%v: expecting %v, got %v", test.name, test.result, result)
+		}
+	}
+	full := NewV4Set(V4Range{0, maxV4})
+	top := NewV4Set(V4Range{maxV4 - 10, maxV4})
+	edgeCases := []testCase{
+		testCase{"union with the whole space", full.Union(NewV4Set(V4Range{5, 10})), "[0.0.0.0-255.255.255.255]"},
+		testCase{"union at the top", top.Union(NewV4Set(V4Range{maxV4 - 5, maxV4 - 2})), "[255.255.255.245-255.255.255.255]"},
+		testCase{"union touching the top", NewV4Set(V4Range{maxV4 - 5, maxV4 - 2}).Union(top), "[255.255.255.245-255.255.255.255]"},
+		testCase{"add at the top", NewV4Set(V4Range{maxV4 - 10, maxV4}, V4Range{maxV4 - 5, maxV4 - 2}), "[255.255.255.245-255.255.255.255]"},
+		testCase{"intersect at the top", top.Intersect(a), "[255.255.255.245-255.255.255.255]"},
+		testCase{"subtract at the top", a.Subtract(top), "[10.0.0.0-10.0.2.255 255.255.255.0-255.255.255.244]"},
+	}
+	for _, test := range edgeCases {
+		if result := test.set.String(); result != test.result {
+			t.Errorf("%v: expecting %v, got %v", test.name, test.result, result)
+		}
+	}
+	if size := full.Union(NewV4Set(V4Range{5, 10})).Size(); size != 1<<32 {
+		t.Errorf("unexpected size of the whole space %v", size)
+	}
+	if a.Size() != 3*256+256 {
+		t.Errorf("unexpected size %v", a.Size())
+	}
+	if !a.Contains(0x0a000100) || a.Contains(0x0a000300) || !a.Contains(maxV4) {
+		t.Errorf("unexpected membership in %v", a)
+	}
+}
+
+func TestV4SetMatchesIPSet(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	randomRange := func() V4Range {
+		first := rnd.Uint32() % 2000
+		return V4Range{first, first + rnd.Uint32()%100}
+	}
+	for i := 0; i < 100; i++ {
+		var a, b V4Set
+		var ipsA IPSet
+		for j := 0; j < 10; j++ {
+			r := randomRange()
+			a.Add(r)
+			ipsA.AddRange(r.IPRange())
+			b.Add(randomRange())
+		}
+		var ranges []IPRange
+		for _, r := range a.Ranges() {
+			ranges = append(ranges, r.IPRange())
+		}
+		if fmt.Sprint(ranges) != fmt.Sprint(ipsA.Ranges()) {
+			t.Errorf("expecting %v, got %v", ipsA.Ranges(), ranges)
+		}
+		union, intersection, difference := a.Union(&b), a.Intersect(&b), a.Subtract(&b)
+		for v := uint32(0); v < 2200; v++ {
+			inA, inB := a.Contains(v), b.Contains(v)
+			if union.Contains(v) != (inA || inB) || intersection.Contains(v) != (inA && inB) || difference.Contains(v) != (inA && !inB) {
+				t.Fatalf("wrong set operation result for %v with %v and %v", V4ToIP(v), a.String(), b.String())
+			}
+		}
+	}
+}
+
+func benchmarkSets() (*V4Set, *IPSet) {
+	rnd := rand.New(rand.NewSource(1))
+	v4 := &V4Set{}
+	generic := &IPSet{}
+	for i := 0; i < 10000; i++ {
+		first := rnd.Uint32()
+		r := V4Range{first, first + rnd.Uint32()%256}
+		if r.First > r.Last {
+			continue
+		}
+		v4.Add(r)
+		generic.AddRange(r.IPRange())
+	}
+	return v4, generic
+}
+
+func BenchmarkV4SetContains(b *testing.B) {
+	set, _ := benchmarkSets()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		set.Contains(uint32(i) * 2654435761)
+	}
+}
+
+func BenchmarkIPSetContains(b *testing.B) {
+	_, set := benchmarkSets()
+	ips := make([]net.IP, 1024)
+	for i := range ips {
+		ips[i] = V4ToIP(uint32(i) * 2654435761)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		set.Contains(ips[i%len(ips)])
+	}
+}
+
+func BenchmarkV4RangeEach(b *testing.B) {
+	r := V4Range{0x0a000000, 0x0a00ffff}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var sum uint32
+		r.Each(func(v uint32) bool {
+			sum += v
+			return true
+		})
+	}
+}
+
+func BenchmarkIPRangeIterator(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		iter := GetIPRangeIterator(net.IP{10, 0, 0, 0}, net.IP{10, 0, 255, 255})
+		for _, ok := iter.Next(); ok; _, ok = iter.Next() {
+		}
+	}
+}
+
+func BenchmarkV4SetUnion(b *testing.B) {
+	set, _ := benchmarkSets()
+	other := NewV4Set(V4Range{0, 1 << 20}, V4Range{1 << 30, 1<<30 + 1<<20})
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		set.Union(other)
+	}
+}
@@ -0,0 +1,78 @@
+// SPDX-License-Identifier: MIT-0
+
+package iputils
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// IsIPv4 reports whether ip is an IPv4 address, either in the 4-byte or in
+// the IPv4-mapped 16-byte form.
+func IsIPv4(ip net.IP) bool {
+	return ip.To4() != nil
+}
+
+// IsIPv6 reports whether ip is an IPv6 address which is not an IPv4-mapped
+// one.
+func IsIPv6(ip net.IP) bool {
+	return len(ip) == IPv6Size && ip.To4() == nil
+}
+
+// SplitByFamily splits the list into IPv4 and IPv6 addresses keeping their
+// order.  IPv4-mapped IPv6 addresses go to the IPv4 list, invalid addresses
+// are dropped.  The addresses are not copied.
+func SplitByFamily(ips []net.IP) (v4, v6 []net.IP) {
+	for _, ip := range ips {
+		switch {
+		case IsIPv4(ip):
+			v4 = append(v4, ip)
+		case IsIPv6(ip):
+			v6 = append(v6, ip)
+		}
+	}
+	return v4, v6
+}
+
+// IsValidIPv4String reports whether s is an IPv4 address in the dotted
+// decimal notation.
+func IsValidIPv4String(s string) bool {
+	return !strings.Contains(s, ":") && net.ParseIP(s) != nil
+}
+
+// IsValidIPv6String reports whether s is an IPv6 address in any of its text
+// forms, including the IPv4-mapped one (e.g. "::ffff:192.0.2.1").
+func IsValidIPv6String(s string) bool {
+	return strings.Contains(s, ":") && net.ParseIP(s) != nil
+}
+
+// IsValidCIDRString reports whether s is a network in CIDR notation.
+func IsValidCIDRString(s string) bool {
+	_, _, err := net.ParseCIDR(s)
+	return err == nil
+}
+
+// ParseIPv4 parses an IPv4 address in the dotted decimal notation and
+// returns it in the 4-byte form.  Unlike net.ParseIP, it fails for IPv6
+// text, including the IPv4-mapped form.
+func ParseIPv4(s string) (net.IP, error) {
+	if !IsValidIPv4String(s) {
+		return nil, fmt.Errorf("%w: IPv4 address %q", ErrInvalidIP, s)
+	}
+	return net.ParseIP(s).To4(), nil
+}
+
+// ParseIPv6 parses an IPv6 address.  Unlike net.ParseIP, it fails for IPv4
+// text and for IPv4-mapped IPv6 addresses (e.g. "::ffff:192.0.2.1"), which
+// net.IP can't tell apart from IPv4 ones.
+func ParseIPv6(s string) (net.IP, error) {
+	if !IsValidIPv6String(s) {
+		return nil, fmt.Errorf("%w: IPv6 address %q", ErrInvalidIP, s)
+	}
+	ip := net.ParseIP(s)
+	if ip.To4() != nil {
+		return nil, fmt.Errorf("%w: IPv4-mapped address %q is not accepted as IPv6 address", ErrFamilyMismatch, s)
+	}
+	return ip, nil
+}
@@ -0,0 +1,134 @@
+// SPDX-License-Identifier: MIT-0
+
+package iputils
+
+import (
+	"fmt"
+	"net"
+	"testing"
+)
+
+func TestIsIPv4IsIPv6(t *testing.T) {
+	type testCase struct {
+		ip   net.IP
+		isV4 bool
+		isV6 bool
+	}
+	cases := []testCase{
+		testCase{net.IP{192, 0, 2, 1}, true, false},
+		testCase{net.ParseIP("192.0.2.1"), true, false},
+		testCase{net.ParseIP("::ffff:192.0.2.1"), true, false},
+		testCase{net.ParseIP("::192.0.2.1"), false, true},
+		testCase{net.ParseIP("2001:db8::1"), false, true},
+		testCase{net.ParseIP("::"), false, true},
+		testCase{nil, false, false},
+		testCase{net.IP{1, 2, 3}, false, false},
+	}
+	for _, test := range cases {
+		if isV4 := IsIPv4(test.ip); test.isV4 != isV4 {
+			t.Errorf("expecting IsIPv4 %v, got %v for %v", test.isV4, isV4, test.ip)
+		}
+		if isV6 := IsIPv6(test.ip); test.isV6 != isV6 {
+			t.Errorf("expecting IsIPv6 %v, got %v for %v", test.isV6, isV6, test.ip)
+		}
+	}
+}
+
+func TestSplitByFamily(t *testing.T) {
+	ips := []net.IP{
+		net.ParseIP("2001:db8::1"),
+		net.IP{192, 0, 2, 1},
+		nil,
+		net.ParseIP("::ffff:10.0.0.1"),
+		net.ParseIP("::1"),
+		net.IP{1, 2, 3},
+	}
+	v4, v6 := SplitByFamily(ips)
+	if fmt.Sprint(v4) != "[192.0.2.1 10.0.0.1]" {
+		t.Errorf("unexpected IPv4 addresses %v", v4)
+	}
+	if fmt.Sprint(v6) != "[2001:db8::1 ::1]" {
+		t.Errorf("unexpected IPv6 addresses %v", v6)
+	}
+	if v4, v6 := SplitByFamily(nil); v4 != nil || v6 != nil {
+		t.Errorf("expecting nothing for an empty list, got %v and %v", v4, v6)
+	}
+}
+
+func TestIsValidStrings(t *testing.T) {
+	type testCase struct {
+		input  string
+		isV4   bool
+		isV6   bool
+		isCIDR bool
+	}
+	cases := []testCase{
+		testCase{"192.0.2.1", true, false, false},
+		testCase{"192.0.2.256", false, false, false},
+		testCase{"2001:db8::1", false, true, false},
+		testCase{"::ffff:192.0.2.1", false, true, false},
+		testCase{"::", false, true, false},
+		testCase{"192.0.2.0/24", false, false, true},
+		testCase{"2001:db8::/32", false, false, true},
+		testCase{"192.0.2.0/33", false, false, false},
+		testCase{"fe80::1%eth0", false, false, false},
+		testCase{"", false, false, false},
+		testCase{"localhost", false, false, false},
+	}
+	for _, test := range cases {
+		if result := IsValidIPv4String(test.input); test.isV4 != result {
+			t.Errorf("expecting IsValidIPv4String %v, got %v for %q", test.isV4, result, test.input)
+		}
+		if result := IsValidIPv6String(test.input); test.isV6 != result {
+			t.Errorf("expecting IsValidIPv6String %v, got %v for %q", test.isV6, result, test.input)
+		}
+		if result := IsValidCIDRString(test.input); test.isCIDR != result {
+			t.Errorf("expecting IsValidCIDRString %v, got %v for %q", test.isCIDR, result, test.input)
+		}
+	}
+}
+
+func TestParseIPv4(t *testing.T) {
+	type testCase struct {
+		input  string
+		result net.IP
+	}
+	cases := []testCase{
+		testCase{"192.0.2.1", net.IP{192, 0, 2, 1}},
+		testCase{"0.0.0.0", net.IP{0, 0, 0, 0}},
+		testCase{"::ffff:192.0.2.1", nil},
+		testCase{"2001:db8::1", nil},
+		testCase{"192.0.2.01", nil},
+		testCase{"192.0.2", nil},
+		testCase{"", nil},
+	}
+	for _, test := range cases {
+		result, err := ParseIPv4(test.input)
+		if (err == nil) != (test.result != nil) || !test.result.Equal(result) || len(test.result) != len(result) {
+			t.Errorf("expecting %v, got (%v, %v) when parsing %q", test.result, result, err, test.input)
+		}
+	}
+}
+
+func TestParseIPv6(t *testing.T) {
+	type testCase struct {
+		input  string
+		result net.IP
+	}
+	cases := []testCase{
+		testCase{"2001:db8::1", net.ParseIP("2001:db8::1")},
+		testCase{"::", net.ParseIP("::")},
+		testCase{"::192.0.2.1", net.ParseIP("::c000:201")},
+		testCase{"::ffff:192.0.2.1", nil},
+		testCase{"::ffff:c000:201", nil},
+		testCase{"192.0.2.1", nil},
+		testCase{"2001:db8::g", nil},
+		testCase{"", nil},
+	}
+	for _, test := range cases {
+		result, err := ParseIPv6(test.input)
+		if (err == nil) != (test.result != nil) || !test.result.Equal(result) || len(test.result) != len(result) {
+			t.Errorf("expecting %v, got (%v, %v) when parsing %q", test.result, result, err, test.input)
+		}
+	}
+}